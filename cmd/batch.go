@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	. "github.com/Ackites/KillWxapkg/internal/cmd"
+	. "github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/restore"
+	"github.com/Ackites/KillWxapkg/internal/sink"
+	"github.com/Ackites/KillWxapkg/internal/source"
+	"github.com/Ackites/KillWxapkg/internal/unpack"
+)
+
+// 同时处理的小程序数量
+const batchConcurrency = 4
+
+// BatchResult 单个小程序的处理结果
+type BatchResult struct {
+	AppID     string          `json:"appid"`
+	Output    string          `json:"output"`
+	Succeeded []string        `json:"succeeded"`
+	Failed    []BatchFailed   `json:"failed"`
+	Conflicts []sink.Conflict `json:"conflicts,omitempty"`
+}
+
+// BatchFailed 处理失败的文件
+type BatchFailed struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// Batch 按清单并发处理多个小程序，每个小程序使用独立的会话，最后输出汇总结果
+func Batch(manifestPath string, opts Options) error {
+	policy, err := sink.ParseConflictPolicy(opts.Conflict)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := LoadBatchManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	// 各会话共用的配置
+	settings := opts.settings()
+	options := newOptions(opts.Include, opts.Exclude, opts.Salvage)
+
+	results := make([]*BatchResult, len(manifest.Apps))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, app := range manifest.Apps {
+		wg.Add(1)
+		go func(i int, app BatchApp) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			appOptions := *options
+			appOptions.Session = NewSession(settings)
+			results[i] = runBatchApp(app, opts.FileExt, opts.RestoreDir, opts.Save, policy, &appOptions)
+		}(i, app)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if len(result.Failed) > 0 || len(result.Succeeded) == 0 {
+			failed++
+		}
+	}
+
+	if opts.JSON {
+		if err := writeJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printBatchSummary(results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 个小程序处理失败", failed, len(results))
+	}
+	return nil
+}
+
+// runBatchApp 处理清单中的一个小程序
+func runBatchApp(app BatchApp, fileExt string, restoreDir bool, save bool, policy sink.ConflictPolicy, options *unpack.Options) *BatchResult {
+	result := &BatchResult{AppID: app.AppID, Output: app.Output}
+
+	var sources []*source.Source
+	for _, input := range app.Inputs {
+		found, err := source.Collect(input, fileExt)
+		if err != nil {
+			result.Failed = append(result.Failed, BatchFailed{File: input, Error: err.Error()})
+			continue
+		}
+		if len(found) == 0 {
+			result.Failed = append(result.Failed, BatchFailed{File: input, Error: "未找到任何文件"})
+		}
+		sources = append(sources, found...)
+	}
+	if len(sources) == 0 {
+		return result
+	}
+
+	if result.AppID == "" {
+		result.AppID = InferAppID(sources[0].Path)
+	}
+	if result.Output == "" {
+		result.Output = DetermineOutputDir(app.Inputs[0], result.AppID)
+	}
+
+	log.Printf("开始处理小程序 %s: %d 个文件, 输出到 %s\n", result.AppID, len(sources), result.Output)
+	options.Session.UseOutput(sink.ForPath(result.Output), policy)
+	defer closeOutput(options.Session.Output)
+
+	errs := processFiles(sources, result.Output, result.AppID, save, options)
+	for i, err := range errs {
+		if err != nil {
+			result.Failed = append(result.Failed, BatchFailed{File: sources[i].Name, Error: err.Error()})
+		} else {
+			result.Succeeded = append(result.Succeeded, sources[i].Name)
+		}
+	}
+
+	restore.ProjectStructure(options.Session, result.Output, restoreDir)
+	result.Conflicts = options.Session.Merger.Conflicts()
+	return result
+}
+
+// printBatchSummary 以表格形式输出批量处理结果
+func printBatchSummary(results []*BatchResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "AppID\t成功\t失败\t冲突\t输出目录")
+	for _, result := range results {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", batchAppName(result), len(result.Succeeded), len(result.Failed), len(result.Conflicts), result.Output)
+	}
+	_ = w.Flush()
+
+	for _, result := range results {
+		for _, failed := range result.Failed {
+			fmt.Printf("失败: [%s] %s: %s\n", batchAppName(result), failed.File, failed.Error)
+		}
+		for _, conflict := range result.Conflicts {
+			fmt.Printf("冲突: [%s] %s\n", batchAppName(result), conflict)
+		}
+	}
+}
+
+// batchAppName 汇总中显示的小程序名称，AppID 未知时显示为 -
+func batchAppName(result *BatchResult) string {
+	if result.AppID == "" {
+		return "-"
+	}
+	return result.AppID
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Ackites/KillWxapkg/internal/carve"
+	. "github.com/Ackites/KillWxapkg/internal/cmd"
+)
+
+// carveResult 单个输入文件的提取结果
+type carveResult struct {
+	Input      string            `json:"input"`
+	Candidates []carve.Candidate `json:"candidates"`
+}
+
+// Carve 从内存转储、备份、磁盘镜像等任意文件中查找并提取嵌入的 wxapkg 包
+// 每个通过校验的包保存为单独的文件，偏移量等信息记录在输出目录下的 carve.json 中
+func Carve(appID, input, outputDir, fileExt string) error {
+	inputFiles := ParseInput(input, fileExt)
+	if len(inputFiles) == 0 {
+		return fmt.Errorf("未找到任何文件")
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Join(filepath.Dir(inputFiles[0]), "carved")
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %v", err)
+	}
+
+	var results []carveResult
+	found := 0
+	for _, inputFile := range inputFiles {
+		candidates, err := carveFile(inputFile, outputDir, appID)
+		if err != nil {
+			return fmt.Errorf("扫描文件 %s 失败: %w", inputFile, err)
+		}
+		for _, candidate := range candidates {
+			if candidate.Valid() {
+				found++
+			}
+		}
+		results = append(results, carveResult{Input: inputFile, Candidates: candidates})
+	}
+
+	manifest := filepath.Join(outputDir, "carve.json")
+	content, err := json.MarshalIndent(results, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifest, content, 0755); err != nil {
+		return fmt.Errorf("保存提取清单失败: %v", err)
+	}
+	log.Printf("提取清单已保存到: %s\n", manifest)
+
+	if found == 0 {
+		return fmt.Errorf("未找到任何wxapkg包")
+	}
+	log.Printf("共提取 %d 个wxapkg包\n", found)
+	return nil
+}
+
+// carveFile 扫描单个文件并提取找到的包
+func carveFile(inputFile, outputDir, appID string) ([]carve.Candidate, error) {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Printf("关闭文件 %s 失败: %v\n", f.Name(), err)
+		}
+	}(f)
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件信息失败: %v", err)
+	}
+
+	var appIDs []string
+	if appID != "" {
+		appIDs = append(appIDs, appID)
+	}
+	for _, id := range CandidateAppIDs(inputFile) {
+		if id != appID {
+			appIDs = append(appIDs, id)
+		}
+	}
+
+	log.Printf("开始扫描文件: %s\n", inputFile)
+	candidates, err := carve.Scan(f, stat.Size(), appIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		if !candidate.Valid() {
+			log.Printf("偏移量 0x%x 处的加密包无法提取: %s\n", candidate.Offset, candidate.Error)
+			continue
+		}
+
+		name := fmt.Sprintf("%s_0x%x.wxapkg", filepath.Base(inputFile), candidate.Offset)
+		candidate.File = filepath.Join(outputDir, name)
+		if err := saveSection(candidate.File, io.NewSectionReader(f, candidate.Offset, candidate.Length)); err != nil {
+			return nil, fmt.Errorf("保存文件 %s 失败: %v", candidate.File, err)
+		}
+		log.Printf("偏移量 0x%x 处找到wxapkg包（%d 个文件, %d 字节），已保存到: %s\n", candidate.Offset, candidate.FileCount, candidate.Length, candidate.File)
+	}
+
+	return candidates, nil
+}
+
+// saveSection 将数据写入文件
+func saveSection(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Printf("关闭文件 %s 失败: %v\n", f.Name(), err)
+		}
+	}(f)
+
+	_, err = io.Copy(f, r)
+	return err
+}
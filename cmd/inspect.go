@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	. "github.com/Ackites/KillWxapkg/internal/cmd"
+	"github.com/Ackites/KillWxapkg/internal/source"
+	"github.com/Ackites/KillWxapkg/internal/util"
+)
+
+// 概要信息中列出的最大文件数量
+const largestCount = 10
+
+// Info 输出包的类型、索引信息及最大的文件，不解包
+func Info(appID, input, fileExt string, jsonOutput bool) error {
+	sources, err := source.Collect(input, fileExt)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("未找到任何文件")
+	}
+
+	var summaries []*PackageSummary
+	for _, src := range sources {
+		pkg, err := OpenSource(src, appID)
+		if err != nil {
+			return fmt.Errorf("打开文件 %s 失败: %w", src.Name, err)
+		}
+		summaries = append(summaries, Summarize(pkg, largestCount))
+		_ = pkg.Close()
+	}
+
+	if jsonOutput {
+		return writeJSON(summaries)
+	}
+
+	for _, summary := range summaries {
+		printSummary(os.Stdout, summary)
+	}
+	return nil
+}
+
+// List 以表格形式输出包内文件索引，不解包
+func List(appID, input, fileExt string, jsonOutput bool) error {
+	sources, err := source.Collect(input, fileExt)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("未找到任何文件")
+	}
+
+	type listing struct {
+		File    string      `json:"file"`
+		Entries []EntryInfo `json:"entries"`
+	}
+
+	var listings []listing
+	for _, src := range sources {
+		pkg, err := OpenSource(src, appID)
+		if err != nil {
+			return fmt.Errorf("打开文件 %s 失败: %w", src.Name, err)
+		}
+		listings = append(listings, listing{File: src.Name, Entries: Entries(pkg)})
+		_ = pkg.Close()
+	}
+
+	if jsonOutput {
+		return writeJSON(listings)
+	}
+
+	for _, l := range listings {
+		fmt.Printf("%s:\n", l.File)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		_, _ = fmt.Fprintln(w, "偏移量\t大小\t  文件名")
+		var total uint64
+		for _, entry := range l.Entries {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t  %s\n", entry.Offset, util.HumanReadableSize(uint64(entry.Size)), entry.Name)
+			total += uint64(entry.Size)
+		}
+		_ = w.Flush()
+		fmt.Printf("共 %d 个文件, %s\n\n", len(l.Entries), util.HumanReadableSize(total))
+	}
+	return nil
+}
+
+// Cat 将包内单个文件的内容输出到标准输出
+func Cat(appID, input, fileExt, name string) error {
+	sources, err := source.Collect(input, fileExt)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("未找到任何文件")
+	}
+
+	// 多个输入时，输出第一个包含该文件的包
+	for _, src := range sources {
+		pkg, err := OpenSource(src, appID)
+		if err != nil {
+			return fmt.Errorf("打开文件 %s 失败: %w", src.Name, err)
+		}
+
+		entry, err := pkg.Entry(name)
+		if err != nil {
+			_ = pkg.Close()
+			continue
+		}
+
+		_, err = io.Copy(os.Stdout, entry)
+		_ = pkg.Close()
+		return err
+	}
+
+	return fmt.Errorf("未找到文件: %s", name)
+}
+
+// printSummary 以文本形式输出包概要信息
+func printSummary(w io.Writer, s *PackageSummary) {
+	encrypted := "否"
+	if s.Encrypted {
+		encrypted = fmt.Sprintf("是 (AppID: %s)", s.AppID)
+	}
+	wxapkgType := string(s.Type)
+	if wxapkgType == "" {
+		wxapkgType = "未知"
+	}
+	wccVersion := s.WccVersion
+	if wccVersion == "" {
+		wccVersion = "未知"
+	}
+
+	_, _ = fmt.Fprintf(w, "文件: %s\n", s.File)
+	_, _ = fmt.Fprintf(w, "加密: %s\n", encrypted)
+	_, _ = fmt.Fprintf(w, "类型: %s\n", wxapkgType)
+	_, _ = fmt.Fprintf(w, "判断依据: %s\n", strings.Join(s.Evidence, ", "))
+	_, _ = fmt.Fprintf(w, "文件数量: %d\n", s.FileCount)
+	_, _ = fmt.Fprintf(w, "包大小: %s\n", util.HumanReadableSize(uint64(s.Size)))
+	_, _ = fmt.Fprintf(w, "索引段长度: %d (%s)\n", s.IndexInfoLength, util.HumanReadableSize(uint64(s.IndexInfoLength)))
+	_, _ = fmt.Fprintf(w, "数据段长度: %d (%s)\n", s.BodyInfoLength, util.HumanReadableSize(uint64(s.BodyInfoLength)))
+	_, _ = fmt.Fprintf(w, "wcc版本: %s\n", wccVersion)
+	_, _ = fmt.Fprintln(w, "最大的文件:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, entry := range s.Largest {
+		_, _ = fmt.Fprintf(tw, "\t%s\t  %s\n", util.HumanReadableSize(uint64(entry.Size)), entry.Name)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w)
+}
+
+// writeJSON 以 JSON 格式输出到标准输出
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
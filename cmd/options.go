@@ -0,0 +1,40 @@
+package cmd
+
+// Options 解包及还原的命令行参数
+type Options struct {
+	AppID         string // 微信小程序的AppID，为空时尝试从输入路径推断
+	Input         string // 输入文件、目录或归档
+	OutputDir     string // 输出目录，为空时保存到输入目录下以AppID命名的文件夹
+	FileExt       string // 处理的文件后缀
+	RestoreDir    bool   // 是否还原工程目录结构
+	Pretty        bool   // 是否美化输出
+	NoClean       bool   // 是否保留中间文件
+	Save          bool   // 是否保存解密后的文件
+	Sensitive     bool   // 是否获取敏感数据
+	PrivateConfig bool   // 是否生成关闭域名校验的本地项目配置
+	Indent        int    // 还原的WXML缩进的空格数，0 表示使用制表符
+	Width         int    // 还原的WXML单行最大宽度，0 表示不限制
+	Include       string // 仅解包匹配的文件
+	Exclude       string // 不解包匹配的文件
+	Salvage       bool   // 宽松模式，尽可能解包损坏或被截断的包
+	Mobile        bool   // 按移动端的包名分组处理
+	Discover      bool   // 遍历微信缓存目录
+	All           bool   // 与 Discover 一起使用，处理所有版本
+	Conflict      string // 多个包写入同一文件且内容不同时的处理方式
+	JSON          bool   // 以JSON格式输出批量处理的结果
+}
+
+// settings 各会话共用的配置
+func (o Options) settings() map[string]interface{} {
+	return map[string]interface{}{
+		"fileExt":       o.FileExt,
+		"restoreDir":    o.RestoreDir,
+		"pretty":        o.Pretty,
+		"noClean":       o.NoClean,
+		"save":          o.Save,
+		"sensitive":     o.Sensitive,
+		"privateConfig": o.PrivateConfig,
+		"indent":        o.Indent,
+		"width":         o.Width,
+	}
+}
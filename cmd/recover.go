@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	. "github.com/Ackites/KillWxapkg/internal/cmd"
+	"github.com/Ackites/KillWxapkg/internal/recovery"
+	"github.com/Ackites/KillWxapkg/internal/unpack"
+)
+
+// Recover 在缺少 AppID 时恢复加密包异或段中的内容
+// 每个包的内容保存到输出目录下以包名命名的文件夹，恢复结果保存为 <包名>.recover.json
+func Recover(input, outputDir, fileExt string) error {
+	inputFiles := ParseInput(input, fileExt)
+	if len(inputFiles) == 0 {
+		return fmt.Errorf("未找到任何文件")
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Join(filepath.Dir(inputFiles[0]), "recovered")
+	}
+
+	recovered := 0
+	for _, inputFile := range inputFiles {
+		if err := recoverFile(inputFile, outputDir); err != nil {
+			log.Printf("恢复文件 %s 失败: %v\n", inputFile, err)
+			continue
+		}
+		recovered++
+	}
+
+	if recovered == 0 {
+		return fmt.Errorf("没有可恢复的文件")
+	}
+	return nil
+}
+
+// recoverFile 恢复单个加密包
+func recoverFile(inputFile, outputDir string) error {
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %v", err)
+	}
+
+	result, err := recovery.Recover(data)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("开始恢复文件: %s\n", inputFile)
+	log.Println(result.Note)
+	log.Printf("猜测的异或密钥: %s（评分 %.2f），从异或段中恢复了 %d 个索引项\n", result.XorKey, result.Score, result.IndexEntries)
+
+	root := filepath.Join(outputDir, filepath.Base(inputFile))
+	for i := range result.Files {
+		file := &result.Files[i]
+		target, _, err := unpack.SafeJoin(root, file.Name)
+		if err != nil {
+			log.Printf("跳过文件 %q: %v\n", file.Name, err)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %v", err)
+		}
+		if err := os.WriteFile(target, file.Content, 0755); err != nil {
+			return fmt.Errorf("保存文件 %s 失败: %v", target, err)
+		}
+	}
+
+	reportFile := filepath.Join(outputDir, filepath.Base(inputFile)+".recover.json")
+	content, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(reportFile, content, 0755); err != nil {
+		return fmt.Errorf("保存恢复报告失败: %v", err)
+	}
+
+	log.Printf("已恢复 %d 个文件到: %s，恢复报告已保存到: %s\n", len(result.Files), root, reportFile)
+	return nil
+}
@@ -1,32 +1,82 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
-	"sync"
 
 	. "github.com/Ackites/KillWxapkg/internal/cmd"
 	. "github.com/Ackites/KillWxapkg/internal/config"
 	"github.com/Ackites/KillWxapkg/internal/restore"
+	"github.com/Ackites/KillWxapkg/internal/sink"
+	"github.com/Ackites/KillWxapkg/internal/source"
+	"github.com/Ackites/KillWxapkg/internal/unpack"
 )
 
-func Execute(appID, input, outputDir, fileExt string, restoreDir bool, pretty bool, noClean bool, save bool, sensitive bool) {
+// Execute 按命令行参数解包并还原输入的包
+func Execute(opts Options) error {
+	appID, input, outputDir, fileExt := opts.AppID, opts.Input, opts.OutputDir, opts.FileExt
+	restoreDir, save := opts.RestoreDir, opts.Save
+
+	// 多个包写入同一路径时的处理方式
+	policy, err := sink.ParseConflictPolicy(opts.Conflict)
+	if err != nil {
+		return err
+	}
+
 	// 存储配置
 	configManager := NewSharedConfigManager()
 	configManager.Set("appID", appID)
 	configManager.Set("input", input)
 	configManager.Set("outputDir", outputDir)
-	configManager.Set("fileExt", fileExt)
-	configManager.Set("restoreDir", restoreDir)
-	configManager.Set("pretty", pretty)
-	configManager.Set("noClean", noClean)
-	configManager.Set("save", save)
-	configManager.Set("sensitive", sensitive)
+	for key, value := range opts.settings() {
+		configManager.Set(key, value)
+	}
 
-	inputFiles := ParseInput(input, fileExt)
+	// 解包选项
+	options := newOptions(opts.Include, opts.Exclude, opts.Salvage)
+
+	// 指定输出目录时所有结果写入同一个输出目标，以 .zip、.tar.gz 结尾时输出为单个归档
+	options.Session = DefaultSession()
+	var output sink.Sink
+	if outputDir != "" {
+		output = sink.ForPath(outputDir)
+		defer closeOutput(output)
+	}
+	options.Session.UseOutput(output, policy)
+
+	// 遍历缓存目录，按小程序和版本依次处理
+	if opts.Discover {
+		return conflictError(executeDiscover(input, outputDir, fileExt, opts.All, restoreDir, save, options))
+	}
 
-	if len(inputFiles) == 0 {
+	// 移动端的包按小程序分组后依次处理
+	if opts.Mobile {
+		inputFiles := ParseInput(input, fileExt)
+		if len(inputFiles) == 0 {
+			log.Println("未找到任何文件")
+			return nil
+		}
+		return conflictError(executeMobile(inputFiles, input, outputDir, appID, restoreDir, save, options))
+	}
+
+	// 归档和标准输入中的包直接在内存中读取，不解压到磁盘
+	sources, err := source.Collect(input, fileExt)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	if len(sources) == 0 {
 		log.Println("未找到任何文件")
-		return
+		return nil
+	}
+
+	// 未指定 AppID 时，尝试从输入路径推断
+	if appID == "" {
+		appID = InferAppID(sources[0].Path)
+		if appID != "" {
+			log.Printf("从输入路径推断AppID: %s\n", appID)
+		}
 	}
 
 	// 确定输出目录
@@ -34,21 +84,169 @@ func Execute(appID, input, outputDir, fileExt string, restoreDir bool, pretty bo
 		outputDir = DetermineOutputDir(input, appID)
 	}
 
-	var wg sync.WaitGroup
-	for _, inputFile := range inputFiles {
-		wg.Add(1)
-		go func(file string) {
-			defer wg.Done()
-			err := ProcessFile(file, outputDir, appID, save)
-			if err != nil {
-				log.Printf("处理文件 %s 时出错: %v\n", file, err)
-			} else {
-				log.Printf("成功处理文件: %s\n", file)
-			}
-		}(inputFile)
-	}
-	wg.Wait()
+	processFiles(sources, outputDir, appID, save, options)
 
 	// 还原工程目录结构
-	restore.ProjectStructure(outputDir, restoreDir)
+	restore.ProjectStructure(options.CurrentSession(), outputDir, restoreDir)
+
+	return conflictError(reportConflicts(options.Session.Merger))
+}
+
+// newOptions 根据命令行参数创建解包选项
+func newOptions(include, exclude string, salvage bool) *unpack.Options {
+	return &unpack.Options{
+		Include: unpack.ParseGlobs(include),
+		Exclude: unpack.ParseGlobs(exclude),
+		Salvage: salvage,
+	}
+}
+
+// withNewSession 复制解包选项并使用独立的会话，配置从默认会话复制，输出目标和冲突处理方式保持不变
+func withNewSession(options *unpack.Options) *unpack.Options {
+	session := options.CurrentSession()
+	isolated := *options
+	isolated.Session = NewSession(NewSharedConfigManager().GetAll())
+	isolated.Session.UseOutput(session.Output, session.OutputMerger().Policy)
+	return &isolated
+}
+
+// reportConflicts 列出合并时发生的文件冲突，返回因冲突未合并的包的数量
+func reportConflicts(merger *sink.Merger) int {
+	conflicts := merger.Conflicts()
+	if len(conflicts) == 0 {
+		return 0
+	}
+
+	log.Printf("合并时发生 %d 处文件冲突（处理方式: %s）:\n", len(conflicts), merger.Policy)
+	failed := make(map[string]bool)
+	for _, conflict := range conflicts {
+		log.Printf("  %s\n", conflict)
+		if conflict.Policy == sink.ConflictFail {
+			failed[conflict.Incoming] = true
+		}
+	}
+	return len(failed)
+}
+
+// conflictError 存在因冲突未合并的包时返回错误
+func conflictError(failed int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d 个包未合并到输出目录", sink.ErrConflict, failed)
+}
+
+// closeOutput 完成输出，归档类的输出在此时写入磁盘
+func closeOutput(output sink.Sink) {
+	if err := output.Close(); err != nil {
+		log.Printf("保存输出 %s 失败: %v\n", output.Root(), err)
+		return
+	}
+	if _, ok := output.(*sink.Archive); ok {
+		log.Printf("输出已保存到: %s\n", output.Root())
+	}
+}
+
+// executeMobile 将移动端的包按小程序标识和版本号分组，每组解包到单独的目录并一起还原
+// 返回因冲突未合并的包的数量
+func executeMobile(inputFiles []string, input, outputDir, appID string, restoreDir bool, save bool, options *unpack.Options) int {
+	apps, unrecognized := GroupMobilePackages(inputFiles)
+	for _, file := range unrecognized {
+		log.Printf("无法识别的文件名，已跳过: %s\n", file)
+	}
+
+	failed := 0
+	for _, app := range apps {
+		log.Println(DescribeMobileApp(app))
+		appOutputDir := MobileOutputDir(input, outputDir, app)
+
+		appOptions := withNewSession(options)
+		processFiles(fileSources(app.Files), appOutputDir, appID, save, appOptions)
+		restore.ProjectStructure(appOptions.Session, appOutputDir, restoreDir)
+		failed += reportConflicts(appOptions.Session.Merger)
+	}
+	return failed
+}
+
+// executeDiscover 遍历微信缓存目录，每个小程序版本使用目录名中的 AppID 解包到单独的目录并还原
+// 返回因冲突未合并的包的数量
+func executeDiscover(root, outputDir, fileExt string, all bool, restoreDir bool, save bool, options *unpack.Options) int {
+	apps, err := DiscoverApps(root, outputDir, fileExt, all)
+	if err != nil {
+		log.Println(err)
+		return 0
+	}
+	if len(apps) == 0 {
+		log.Println("未找到任何小程序")
+		return 0
+	}
+
+	failed := 0
+	for _, app := range apps {
+		log.Printf("小程序 %s 版本 %s: %d 个包\n", app.AppID, app.Version, len(app.Files))
+		appOutputDir := DiscoverOutputDir(root, outputDir, app, all)
+
+		appOptions := withNewSession(options)
+		processFiles(fileSources(app.Files), appOutputDir, app.AppID, save, appOptions)
+		restore.ProjectStructure(appOptions.Session, appOutputDir, restoreDir)
+		failed += reportConflicts(appOptions.Session.Merger)
+	}
+	return failed
+}
+
+// fileSources 将磁盘上的文件转换为输入源，无法读取的文件跳过
+func fileSources(files []string) []*source.Source {
+	sources := make([]*source.Source, 0, len(files))
+	for _, file := range files {
+		src, err := source.FromFile(file)
+		if err != nil {
+			log.Printf("读取文件 %s 失败: %v\n", file, err)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// 同时解包并暂存的包数量
+const unpackConcurrency = 4
+
+// processFiles 并发解包多个输入源，再按输入顺序依次合并到输出目标，使结果与并发顺序无关
+// 每个包在它之前的包合并完成后立即合并并释放暂存区，同时暂存的包不超过 unpackConcurrency 个
+// 按输入顺序返回每个输入源的错误，成功时为 nil
+func processFiles(sources []*source.Source, outputDir, appID string, save bool, options *unpack.Options) []error {
+	errs := make([]error, len(sources))
+	unpacked := make([]*Unpacked, len(sources))
+	done := make([]chan struct{}, len(sources))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	// 按输入顺序启动解包，合并后才释放名额
+	sem := make(chan struct{}, unpackConcurrency)
+	go func() {
+		for i, src := range sources {
+			sem <- struct{}{}
+			go func(i int, src *source.Source) {
+				defer close(done[i])
+				unpacked[i], errs[i] = UnpackSource(src, outputDir, appID, save, options)
+			}(i, src)
+		}
+	}()
+
+	for i, src := range sources {
+		<-done[i]
+		if errs[i] == nil {
+			errs[i] = MergeUnpacked(unpacked[i], options)
+		}
+		unpacked[i] = nil
+		<-sem
+
+		if errs[i] != nil {
+			log.Printf("处理文件 %s 时出错: %v\n", src.Name, errs[i])
+		} else {
+			log.Printf("成功处理文件: %s\n", src.Name)
+		}
+	}
+	return errs
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	. "github.com/Ackites/KillWxapkg/internal/cmd"
+	"github.com/Ackites/KillWxapkg/internal/source"
+	"github.com/Ackites/KillWxapkg/internal/verify"
+)
+
+// Verify 检查包的结构完整性，不解包，发现问题时返回错误
+func Verify(appID, input, fileExt string, jsonOutput bool) error {
+	sources, err := source.Collect(input, fileExt)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("未找到任何文件")
+	}
+
+	type result struct {
+		File   string         `json:"file"`
+		Issues []verify.Issue `json:"issues"`
+	}
+
+	var results []result
+	var failed int
+	for _, src := range sources {
+		reader, f, _, err := OpenDecrypted(src, appID)
+		if err != nil {
+			return fmt.Errorf("打开文件 %s 失败: %w", src.Name, err)
+		}
+		issues := verify.Verify(reader, reader.Size())
+		_ = f.Close()
+
+		if issues == nil {
+			issues = []verify.Issue{}
+		}
+		if len(issues) > 0 {
+			failed++
+		}
+		results = append(results, result{File: src.Name, Issues: issues})
+	}
+
+	if jsonOutput {
+		if err := writeJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if len(r.Issues) == 0 {
+				fmt.Printf("%s: 通过\n", r.File)
+				continue
+			}
+			fmt.Printf("%s: 发现 %d 个问题\n", r.File, len(r.Issues))
+			for _, issue := range r.Issues {
+				if issue.Name != "" {
+					fmt.Printf("  [%s] %s: %s\n", issue.Kind, issue.Name, issue.Message)
+				} else {
+					fmt.Printf("  [%s] %s\n", issue.Kind, issue.Message)
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个包未通过检查", failed)
+	}
+	return nil
+}
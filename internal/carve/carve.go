@@ -0,0 +1,211 @@
+package carve
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Ackites/KillWxapkg/internal/decrypt"
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
+)
+
+// 加密包的文件头前缀
+const encryptedMark = "V1MMWX"
+
+// 每次扫描读取的数据块大小
+const chunkSize = 1 << 20
+
+// Candidate 在数据中找到的 wxapkg 包
+type Candidate struct {
+	Offset    int64  `json:"offset"`          // 在原始数据中的偏移量
+	Length    int64  `json:"length"`          // 包的长度，无法校验时为 0
+	Encrypted bool   `json:"encrypted"`       // 是否为 V1MMWX 加密包
+	AppID     string `json:"appid,omitempty"` // 解密使用的 AppID
+	FileCount int    `json:"fileCount"`       // 包内文件数量
+	File      string `json:"file,omitempty"`  // 提取后的文件路径
+	Error     string `json:"error,omitempty"` // 无法校验的原因
+}
+
+// Valid 是否通过了索引校验，可以提取
+func (c *Candidate) Valid() bool {
+	return c.Length > 0 && c.Error == ""
+}
+
+// Scan 扫描 r 中嵌入的 wxapkg 包，size 为数据总长度
+// 明文包按 0xBE … 0xED 文件头查找，加密包按 V1MMWX 前缀查找并依次尝试 appIDs 解密
+// 只有索引自洽的包才会返回有效长度，找到有效包后从其末尾继续扫描
+func Scan(r io.ReaderAt, size int64, appIDs []string) ([]Candidate, error) {
+	var candidates []Candidate
+	buf := make([]byte, chunkSize+wxapkg.HeaderSize+2)
+
+	for pos := int64(0); pos < size; {
+		n, err := r.ReadAt(buf, pos)
+		if err != nil && err != io.EOF {
+			return candidates, fmt.Errorf("读取偏移量 %d 处的数据失败: %w", pos, err)
+		}
+		if n == 0 {
+			break
+		}
+
+		// 最后一块之外，与下一块重叠的部分留到下一块处理
+		limit := n
+		if pos+int64(n) < size {
+			limit = min(n, chunkSize)
+		}
+		next := pos + int64(limit)
+
+		for i := 0; i < limit; i++ {
+			var candidate Candidate
+			switch {
+			case buf[i] == wxapkg.FirstMark && i+wxapkg.HeaderSize <= n && buf[i+wxapkg.HeaderSize-1] == wxapkg.LastMark:
+				if !plausibleHeader(buf[i:i+wxapkg.HeaderSize], size-pos-int64(i)) {
+					continue
+				}
+				candidate = probePlain(r, pos+int64(i), size)
+				// 明文文件头标记过于常见，校验失败的不记录
+				if !candidate.Valid() {
+					continue
+				}
+			case buf[i] == encryptedMark[0] && bytes.HasPrefix(buf[i:n], []byte(encryptedMark)):
+				candidate = probeEncrypted(r, pos+int64(i), size, appIDs)
+			default:
+				continue
+			}
+
+			candidates = append(candidates, candidate)
+			if candidate.Valid() {
+				next = candidate.Offset + candidate.Length
+				break
+			}
+		}
+
+		pos = next
+	}
+
+	return candidates, nil
+}
+
+// plausibleHeader 快速排除长度明显不合理的明文文件头
+func plausibleHeader(header []byte, remain int64) bool {
+	indexInfoLength := binary.BigEndian.Uint32(header[5:9])
+	bodyInfoLength := binary.BigEndian.Uint32(header[9:13])
+	return indexInfoLength >= 4 && uint64(indexInfoLength)+uint64(bodyInfoLength) <= uint64(remain)
+}
+
+// probePlain 校验 offset 处的明文包
+func probePlain(r io.ReaderAt, offset, size int64) Candidate {
+	candidate := Candidate{Offset: offset}
+	length, fileCount, err := Validate(io.NewSectionReader(r, offset, size-offset), size-offset)
+	if err != nil {
+		candidate.Error = err.Error()
+		return candidate
+	}
+	candidate.Length = length
+	candidate.FileCount = fileCount
+	return candidate
+}
+
+// probeEncrypted 使用候选 AppID 解密 offset 处的加密包并校验
+func probeEncrypted(r io.ReaderAt, offset, size int64, appIDs []string) Candidate {
+	candidate := Candidate{Offset: offset, Encrypted: true}
+	if len(appIDs) == 0 {
+		candidate.Error = "未指定AppID，无法校验加密包"
+		return candidate
+	}
+
+	head := make([]byte, len(encryptedMark)+16)
+	n, err := r.ReadAt(head, offset)
+	if err != nil && err != io.EOF {
+		candidate.Error = fmt.Sprintf("读取文件头失败: %v", err)
+		return candidate
+	}
+	head = head[:n]
+
+	candidate.Error = fmt.Sprintf("AppID错误，已尝试 %v", appIDs)
+	for _, appID := range appIDs {
+		plainHeader, err := decrypt.DecryptHeader(head, appID)
+		if err != nil {
+			continue
+		}
+
+		// 索引段长度是否包含文件数量在不同来源的包中不一致，两种长度都尝试
+		indexInfoLength := int64(binary.BigEndian.Uint32(plainHeader[5:9]))
+		bodyInfoLength := int64(binary.BigEndian.Uint32(plainHeader[9:13]))
+		for _, plainSize := range []int64{
+			wxapkg.HeaderSize + indexInfoLength + bodyInfoLength,
+			wxapkg.HeaderSize + 4 + indexInfoLength + bodyInfoLength,
+		} {
+			length := decrypt.EncryptedSize(plainSize)
+			if offset+length > size {
+				candidate.Error = fmt.Sprintf("%v: 包长度 %d 超出剩余数据长度 %d", wxapkg.ErrTruncated, length, size-offset)
+				continue
+			}
+
+			reader, err := decrypt.NewReader(io.NewSectionReader(r, offset, length), length, appID)
+			if err != nil {
+				continue
+			}
+			plainLength, fileCount, err := Validate(reader, reader.Size())
+			if err != nil || plainLength != reader.Size() {
+				continue
+			}
+
+			candidate.Length = length
+			candidate.AppID = appID
+			candidate.FileCount = fileCount
+			candidate.Error = ""
+			return candidate
+		}
+	}
+
+	return candidate
+}
+
+// Validate 校验 r 开头的明文包的索引是否自洽，返回包的实际长度和文件数量
+// size 为可用数据的长度，包之后可以有其他数据
+func Validate(r io.ReaderAt, size int64) (int64, int, error) {
+	header, fileList, err := wxapkg.ReadIndex(r, -1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(fileList) == 0 {
+		return 0, 0, fmt.Errorf("包内没有文件")
+	}
+
+	// 索引段实际结束位置，索引段长度可能包含也可能不包含文件数量
+	indexEnd := int64(wxapkg.HeaderSize + 4)
+	for _, file := range fileList {
+		indexEnd += 12 + int64(file.NameLen)
+	}
+	if indexEnd != wxapkg.HeaderSize+int64(header.IndexInfoLength) && indexEnd != wxapkg.HeaderSize+4+int64(header.IndexInfoLength) {
+		return 0, 0, fmt.Errorf("索引段长度 %d 与实际索引结束位置 %d 不符", header.IndexInfoLength, indexEnd)
+	}
+
+	length := indexEnd + int64(header.BodyInfoLength)
+	if length > size {
+		return 0, 0, fmt.Errorf("%w: 包长度 %d 超出剩余数据长度 %d", wxapkg.ErrTruncated, length, size)
+	}
+
+	// 文件必须位于数据段内且互不重叠
+	files := append([]wxapkg.WxapkgFile(nil), fileList...)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Offset < files[j].Offset
+	})
+	end := indexEnd
+	for _, file := range files {
+		if file.Size == 0 {
+			continue
+		}
+		if int64(file.Offset) < end {
+			return 0, 0, fmt.Errorf("文件 %s 的偏移量 %d 与索引段或其他文件重叠", file.Name, file.Offset)
+		}
+		end = int64(file.Offset) + int64(file.Size)
+		if end > length {
+			return 0, 0, fmt.Errorf("文件 %s 超出数据段", file.Name)
+		}
+	}
+
+	return length, len(fileList), nil
+}
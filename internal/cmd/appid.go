@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Ackites/KillWxapkg/internal/decrypt"
+	"github.com/Ackites/KillWxapkg/internal/source"
+)
+
+// 微信小程序 AppID 格式：wx + 16 位十六进制字符
+var appIDRegex = regexp.MustCompile(`wx[0-9a-f]{16}`)
+
+// InferAppID 从路径中推断 AppID，取离文件最近的一级 wx 目录名
+func InferAppID(path string) string {
+	ids := appIDsFromPath(path)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+// CandidateAppIDs 收集候选 AppID：优先路径中的 wx 目录，其次是同级及上级目录中的 wx 文件或目录
+func CandidateAppIDs(inputFile string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(ids []string) {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				candidates = append(candidates, id)
+			}
+		}
+	}
+
+	add(appIDsFromPath(inputFile))
+	add(appIDsFromSiblings(inputFile))
+	return candidates
+}
+
+// appIDsFromPath 按由近及远的顺序提取路径各级名称中的 AppID
+func appIDsFromPath(path string) []string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var ids []string
+	for dir := path; ; {
+		ids = append(ids, appIDRegex.FindAllString(filepath.Base(dir), -1)...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ids
+}
+
+// appIDsFromSiblings 提取文件所在目录及其上两级目录中名称包含 AppID 的文件或目录
+func appIDsFromSiblings(path string) []string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var ids []string
+	dir := filepath.Dir(path)
+	for level := 0; level < 3; level++ {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			for _, entry := range entries {
+				ids = append(ids, appIDRegex.FindAllString(entry.Name(), -1)...)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ids
+}
+
+// ResolveAppID 确定包的 AppID
+// 加密包优先尝试用户指定的 AppID，失败后依次尝试从路径推断的候选 AppID；
+// 未加密的包返回用户指定的 AppID，未指定时返回路径中离文件最近的 AppID
+func ResolveAppID(inputFile, appID string) (string, error) {
+	src, err := source.FromFile(inputFile)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	return resolveAppID(src, appID, false)
+}
+
+// resolveAppID 确定 AppID，salvage 为 true 时不校验文件长度，用于被截断的文件
+func resolveAppID(src *source.Source, appID string, salvage bool) (string, error) {
+	f, err := src.Open()
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer func(f source.File) {
+		err := f.Close()
+		if err != nil {
+			log.Printf("关闭文件 %s 失败: %v\n", src.Name, err)
+		}
+	}(f)
+
+	head := make([]byte, 22)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("读取文件头失败: %v", err)
+	}
+	head = head[:n]
+
+	// 未加密的包无法校验 AppID，未指定时只使用路径中的 AppID，同级目录中的名称可能属于其他小程序
+	if !decrypt.IsEncrypted(head) {
+		if ids := appIDsFromPath(src.Path); appID == "" && len(ids) > 0 {
+			return ids[0], nil
+		}
+		return appID, nil
+	}
+
+	var candidates []string
+	if appID != "" {
+		candidates = append(candidates, appID)
+	}
+	for _, id := range CandidateAppIDs(src.Path) {
+		if id != appID {
+			candidates = append(candidates, id)
+		}
+	}
+
+	size := src.Size
+	if salvage {
+		size = -1
+	}
+	found, err := decrypt.FindAppID(head, size, candidates)
+	if err != nil {
+		return "", err
+	}
+	if appID != "" && found != appID {
+		log.Printf("指定的AppID %s 无法解密 %s，已自动使用 %s\n", appID, filepath.Base(src.Path), found)
+	}
+	return found, nil
+}
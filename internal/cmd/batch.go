@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BatchManifest 批量处理清单
+type BatchManifest struct {
+	Apps []BatchApp `yaml:"apps" json:"apps"`
+}
+
+// BatchApp 清单中的一个小程序
+type BatchApp struct {
+	AppID  string   `yaml:"appid" json:"appid"`   // 未指定时从输入路径推断
+	Inputs []string `yaml:"inputs" json:"inputs"` // 输入文件或目录
+	Output string   `yaml:"output" json:"output"` // 未指定时与单个小程序的默认输出目录相同
+}
+
+// LoadBatchManifest 读取 YAML 或 JSON 格式的批量处理清单
+// 清单中的相对路径以清单所在目录为基准
+func LoadBatchManifest(path string) (*BatchManifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取清单失败: %v", err)
+	}
+
+	var manifest BatchManifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &manifest)
+	} else {
+		err = yaml.Unmarshal(content, &manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析清单失败: %v", err)
+	}
+	if len(manifest.Apps) == 0 {
+		return nil, fmt.Errorf("清单中没有任何小程序")
+	}
+
+	base := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(base, p)
+	}
+	for i := range manifest.Apps {
+		app := &manifest.Apps[i]
+		if len(app.Inputs) == 0 {
+			return nil, fmt.Errorf("清单中第 %d 个小程序未指定输入", i+1)
+		}
+		for j := range app.Inputs {
+			app.Inputs[j] = resolve(app.Inputs[j])
+		}
+		app.Output = resolve(app.Output)
+	}
+
+	return &manifest, nil
+}
@@ -13,6 +13,9 @@ import (
 
 	. "github.com/Ackites/KillWxapkg/internal/config"
 	"github.com/Ackites/KillWxapkg/internal/decrypt"
+	"github.com/Ackites/KillWxapkg/internal/enum"
+	"github.com/Ackites/KillWxapkg/internal/sink"
+	"github.com/Ackites/KillWxapkg/internal/source"
 	"github.com/Ackites/KillWxapkg/internal/unpack"
 )
 
@@ -61,10 +64,34 @@ func DetermineOutputDir(input, appID string) string {
 }
 
 // ProcessFile 合并目录
-func ProcessFile(inputFile, outputDir, appID string, save bool) error {
-	log.Printf("开始处理文件: %s\n", inputFile)
+func ProcessFile(inputFile, outputDir, appID string, save bool, options *unpack.Options) error {
+	src, err := source.FromFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %v", err)
+	}
+	return ProcessSource(src, outputDir, appID, save, options)
+}
+
+// Unpacked 已解包但尚未合并到输出目标的包
+type Unpacked struct {
+	Source  *source.Source
+	Info    *WxapkgInfo
+	Staging sink.Staging // 解包结果，包括解密后的文件和解包报告，合并后关闭
+}
 
-	manager := GetWxapkgManager()
+// ProcessSource 解密并解包一个输入源，归档中的包和标准输入直接在内存中处理
+func ProcessSource(src *source.Source, outputDir, appID string, save bool, options *unpack.Options) error {
+	unpacked, err := UnpackSource(src, outputDir, appID, save, options)
+	if err != nil {
+		return err
+	}
+	return MergeUnpacked(unpacked, options)
+}
+
+// UnpackSource 解密并解包一个输入源，结果暂存在临时目录或内存中，由 MergeUnpacked 合并到输出目标
+// 多个包可以并发解包，再按固定顺序合并，使合并结果与并发顺序无关
+func UnpackSource(src *source.Source, outputDir, appID string, save bool, options *unpack.Options) (unpacked *Unpacked, err error) {
+	log.Printf("开始处理文件: %s\n", src.Name)
 
 	// 初始化 WxapkgInfo
 	info := &WxapkgInfo{
@@ -73,112 +100,172 @@ func ProcessFile(inputFile, outputDir, appID string, save bool) error {
 	}
 
 	// 确定解密后的文件路径
-	decryptedFilePath := filepath.Join(outputDir, filepath.Base(inputFile))
+	decryptedFilePath := filepath.Join(outputDir, outputName(src))
 
-	// 解密
-	decryptedData, err := decrypt.DecryptWxapkg(inputFile, appID)
+	// 校验并确定 AppID
+	salvage := options != nil && options.Salvage
+	appID, err = resolveAppID(src, appID, salvage)
 	if err != nil {
-		return fmt.Errorf("解密失败: %v", err)
+		return nil, err
 	}
+	info.WxAppId = appID
 
-	// 保存解密后的文件
-	err = os.MkdirAll(outputDir, 0755)
+	// 打开输入文件
+	f, err := src.Open()
 	if err != nil {
-		return fmt.Errorf("创建输出目录失败: %v", err)
+		return nil, fmt.Errorf("打开文件失败: %v", err)
 	}
-
-	// 是否保存解密后的文件
-	if save {
-		err = os.WriteFile(decryptedFilePath, decryptedData, 0755)
+	defer func(f source.File) {
+		err := f.Close()
 		if err != nil {
-			return fmt.Errorf("保存解密文件失败: %v", err)
+			log.Printf("关闭文件 %s 失败: %v\n", src.Name, err)
 		}
+	}(f)
 
-		log.Printf("文件解密并保存到: %s\n", decryptedFilePath)
+	// 按需解密，不在内存中生成完整明文
+	newReader := decrypt.NewReader
+	if salvage {
+		newReader = decrypt.NewSalvageReader
+	}
+	reader, err := newReader(f, src.Size, appID)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败: %w", err)
 	}
 
-	// 解包到临时目录
-	tempDir, err := os.MkdirTemp("", "wxapkg")
+	// 解包到暂存区，完成后再合并到输出目标，出错时删除暂存的文件
+	staging, err := newStaging(outputDir, options)
 	if err != nil {
-		return fmt.Errorf("创建临时目录失败: %v", err)
+		return nil, err
 	}
-	defer func(path string) {
-		err := os.RemoveAll(path)
+	defer func() {
 		if err != nil {
-			log.Printf("删除临时目录 %s 失败: %v\n", path, err)
+			_ = staging.Close()
 		}
-	}(tempDir)
+	}()
 
-	// 包文件列表
-	var filelist []string
-
-	filelist, err = unpack.UnpackWxapkg(decryptedData, tempDir)
+	report, err := unpack.UnpackWxapkg(reader, reader.Size(), staging, options)
 	if err != nil {
-		return fmt.Errorf("解包失败: %v", err)
+		return nil, fmt.Errorf("解包失败: %w", err)
 	}
 
+	// 包文件列表
+	filelist := report.Files
+
 	// 设置解包状态
 	info.IsExtracted = true
 
-	// 合并解包后的内容到输出目录
-	err = mergeDirs(tempDir, outputDir)
-	if err != nil {
-		return fmt.Errorf("合并目录失败: %v", err)
-	}
-
 	info.WxapkgType = util.GetWxapkgType(filelist)
 
-	if restore.IsMainPackage(info) {
+	if restore.IsMainPackage(info) || restore.IsPlugin(info) {
 		info.SourcePath = outputDir
-	} else if restore.IsSubpackage(info) {
+	} else if restore.IsSubpackage(info) && len(filelist) > 0 {
 		info.SourcePath = filelist[0]
 	}
 
-	// 将包信息添加到管理器中
-	manager.AddPackage(info.SourcePath, info)
-
-	return nil
-}
+	// 小游戏插件与主包的文件同名，按包名放到 plugin 目录下单独的子目录中
+	if info.WxapkgType == enum.GAME_PLUGIN {
+		name := filepath.Base(src.Path)
+		info.SourcePath = filepath.Join(outputDir, enum.PluginRoot, strings.TrimSuffix(name, filepath.Ext(name)))
+		if err := staging.MoveInto(info.SourcePath); err != nil {
+			return nil, fmt.Errorf("移动插件目录失败: %w", err)
+		}
+	}
 
-// mergeDirs 合并目录
-func mergeDirs(srcDir, dstDir string) error {
-	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// 还原工程目录结构时，小程序插件按开发者工具的插件工程放到 plugin 目录下
+	if restoreDir, ok := options.CurrentSession().Config.Get("restoreDir"); ok && restoreDir.(bool) && info.WxapkgType == enum.APP_PLUGIN_V1 {
+		info.SourcePath = filepath.Join(outputDir, enum.PluginRoot)
+		if err := staging.MoveInto(info.SourcePath); err != nil {
+			return nil, fmt.Errorf("移动插件目录失败: %w", err)
 		}
-		relPath, err := filepath.Rel(srcDir, path)
+	}
+
+	// 是否保存解密后的文件
+	if save {
+		err = saveDecrypted(staging, decryptedFilePath, reader)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("保存解密文件失败: %v", err)
 		}
-		targetPath := filepath.Join(dstDir, relPath)
 
-		if info.IsDir() {
-			return os.MkdirAll(targetPath, 0755)
-		}
+		log.Printf("文件解密并保存到: %s\n", decryptedFilePath)
+	}
 
-		srcFile, err := os.Open(path)
-		if err != nil {
-			return err
+	// 记录重命名和跳过的文件
+	if report.HasIssues() {
+		for _, rename := range report.Renamed {
+			log.Printf("文件 %q 已重命名为 %q: %s\n", rename.Original, rename.Sanitized, strings.Join(rename.Reasons, ", "))
 		}
-		defer func(srcFile *os.File) {
-			err := srcFile.Close()
-			if err != nil {
-				log.Printf("关闭文件 %s 失败: %v\n", srcFile.Name(), err)
-			}
-		}(srcFile)
+		for _, rejected := range report.Rejected {
+			log.Printf("文件 %q 已跳过: %s\n", rejected.Original, strings.Join(rejected.Reasons, ", "))
+		}
+		for _, indexError := range report.IndexErrors {
+			log.Printf("索引异常: %s\n", indexError)
+		}
+		if len(report.Recovery) > 0 {
+			counts := report.Recovered()
+			log.Printf("恢复结果: 完整 %d, 截断 %d, 跳过 %d, 失败 %d\n",
+				counts[unpack.RecoveryOK], counts[unpack.RecoveryTruncated], counts[unpack.RecoverySkipped], counts[unpack.RecoveryFailed])
+		}
+		reportFile := filepath.Join(outputDir, outputName(src)+".report.json")
+		if err := report.Save(staging, reportFile); err != nil {
+			log.Printf("保存解包报告失败: %v\n", err)
+		} else {
+			log.Printf("解包报告已保存到: %s\n", reportFile)
+		}
+	}
 
-		dstFile, err := os.Create(targetPath)
-		if err != nil {
-			return err
+	return &Unpacked{Source: src, Info: info, Staging: staging}, nil
+}
+
+// MergeUnpacked 将解包结果合并到会话的输出目标，并将包信息添加到管理器中
+// 与已合并的文件内容不同时按会话的冲突处理方式处理
+func MergeUnpacked(unpacked *Unpacked, options *unpack.Options) error {
+	session := options.CurrentSession()
+
+	// 合并解包后的内容到输出目标，完成后删除暂存的文件
+	err := session.OutputMerger().Merge(unpacked.Staging, unpacked.Source.Name)
+	if closeErr := unpacked.Staging.Close(); closeErr != nil {
+		log.Printf("删除暂存文件失败: %v\n", closeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("合并目录失败: %w", err)
+	}
+
+	// 将包信息添加到管理器中，按输入源区分，主包与插件等目录相同的包不会相互覆盖
+	session.Packages.AddPackage(unpacked.Source.Name, unpacked.Info)
+
+	return nil
+}
+
+// newStaging 创建解包结果的暂存区，输出到磁盘目录时暂存在临时目录中，避免整个包占用内存
+// 输出为归档时结果本身保存在内存中，暂存区同样使用内存
+func newStaging(outputDir string, options *unpack.Options) (sink.Staging, error) {
+	if _, ok := options.CurrentSession().OutputSink().(*sink.Dir); ok {
+		return sink.NewTempDir(outputDir)
+	}
+	return sink.NewMemory(outputDir), nil
+}
+
+// outputName 解密后的文件和解包报告相对于输出目录的路径
+// 归档中的包保留包内路径，不同目录中的同名包不会相互覆盖，其他输入源使用文件名
+func outputName(src *source.Source) string {
+	if src.Entry != "" {
+		if name, _, err := unpack.SanitizeName("/" + src.Entry); err == nil {
+			return filepath.FromSlash(name)
 		}
-		defer func(dstFile *os.File) {
-			err := dstFile.Close()
-			if err != nil {
-				log.Printf("关闭文件 %s 失败: %v\n", dstFile.Name(), err)
-			}
-		}(dstFile)
+	}
+	return filepath.Base(src.Path)
+}
 
-		_, err = io.Copy(dstFile, srcFile)
+// saveDecrypted 将解密后的内容流式写入输出目标
+func saveDecrypted(output sink.Sink, path string, reader *decrypt.Reader) error {
+	w, err := output.Create(path)
+	if err != nil {
 		return err
-	})
+	}
+
+	_, err = io.Copy(w, io.NewSectionReader(reader, 0, reader.Size()))
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
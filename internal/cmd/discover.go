@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// appDirRegex 缓存目录中以 AppID 命名的目录
+var appDirRegex = regexp.MustCompile(`^` + appIDRegex.String() + `$`)
+
+// versionDirRegex 小程序目录下以版本号命名的目录
+var versionDirRegex = regexp.MustCompile(`^\d+$`)
+
+// DiscoveredApp 缓存目录中找到的小程序的一个版本
+type DiscoveredApp struct {
+	AppID   string
+	Version string
+	Dir     string   // 版本目录
+	Files   []string // 版本目录下的包
+}
+
+// DiscoverApps 遍历微信缓存目录，查找所有 wx…/<版本号>/ 目录中的包
+// all 为 false 时每个小程序只保留版本号最大的版本；输出目录位于缓存目录中时跳过，避免再次处理已还原的结果
+func DiscoverApps(root, outputDir, fileExt string, all bool) ([]*DiscoveredApp, error) {
+	var apps []*DiscoveredApp
+	output := discoverOutputRoot(root, outputDir)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 无法访问的目录跳过，不中断遍历
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && samePath(path, output) {
+			return fs.SkipDir
+		}
+		if !appDirRegex.MatchString(d.Name()) {
+			return nil
+		}
+
+		versions, err := discoverVersions(path, d.Name(), fileExt)
+		if err != nil {
+			return err
+		}
+		if !all && len(versions) > 1 {
+			versions = versions[len(versions)-1:]
+		}
+		apps = append(apps, versions...)
+		return fs.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历缓存目录失败: %w", err)
+	}
+
+	return apps, nil
+}
+
+// discoverVersions 查找小程序目录下包含包文件的版本目录，按版本号从小到大排列
+func discoverVersions(appDir, appID, fileExt string) ([]*DiscoveredApp, error) {
+	entries, err := os.ReadDir(appDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*DiscoveredApp
+	for _, entry := range entries {
+		if !entry.IsDir() || !versionDirRegex.MatchString(entry.Name()) {
+			continue
+		}
+
+		dir := filepath.Join(appDir, entry.Name())
+		var files []string
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), fileExt) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		versions = append(versions, &DiscoveredApp{AppID: appID, Version: entry.Name(), Dir: dir, Files: files})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersion(versions[i].Version, versions[j].Version) < 0
+	})
+	return versions, nil
+}
+
+// compareVersion 按数值比较纯数字的版本号，不受 uint64 范围限制
+func compareVersion(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// DiscoverOutputDir 确定找到的小程序的输出目录
+// 未指定输出目录时保存到缓存目录下的 result 文件夹，保留全部版本时按版本号再分一级
+func DiscoverOutputDir(root, outputDir string, app *DiscoveredApp, all bool) string {
+	dir := filepath.Join(discoverOutputRoot(root, outputDir), app.AppID)
+	if all {
+		dir = filepath.Join(dir, app.Version)
+	}
+	return dir
+}
+
+// discoverOutputRoot 所有小程序的输出目录，未指定时为缓存目录下的 result 文件夹
+func discoverOutputRoot(root, outputDir string) string {
+	if outputDir == "" {
+		return filepath.Join(root, "result")
+	}
+	return outputDir
+}
+
+// samePath 两个路径是否指向同一位置
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Ackites/KillWxapkg/internal/decrypt"
+	"github.com/Ackites/KillWxapkg/internal/enum"
+	"github.com/Ackites/KillWxapkg/internal/source"
+	"github.com/Ackites/KillWxapkg/internal/util"
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
+)
+
+// PackageSummary 包的概要信息
+type PackageSummary struct {
+	File            string          `json:"file"`
+	AppID           string          `json:"appid,omitempty"`
+	Encrypted       bool            `json:"encrypted"`
+	Type            enum.WxapkgType `json:"type"`
+	Evidence        []string        `json:"evidence"`
+	FileCount       int             `json:"fileCount"`
+	Size            int64           `json:"size"`
+	IndexInfoLength uint32          `json:"indexInfoLength"`
+	BodyInfoLength  uint32          `json:"bodyInfoLength"`
+	WccVersion      string          `json:"wccVersion,omitempty"`
+	Largest         []EntryInfo     `json:"largest"`
+}
+
+// EntryInfo 包内文件信息
+type EntryInfo struct {
+	Name   string `json:"name"`
+	Offset uint32 `json:"offset"`
+	Size   uint32 `json:"size"`
+}
+
+// Package 已打开的包
+type Package struct {
+	*wxapkg.Archive
+	File      string
+	AppID     string
+	Encrypted bool
+	closer    io.Closer
+}
+
+// Close 关闭底层文件
+func (p *Package) Close() error {
+	return p.closer.Close()
+}
+
+// OpenPackage 打开 wxapkg 文件，加密包按需解密，不会写入磁盘
+func OpenPackage(inputFile, appID string) (*Package, error) {
+	src, err := source.FromFile(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	return OpenSource(src, appID)
+}
+
+// OpenSource 打开输入源中的包，归档中的包和标准输入同样按需解密
+func OpenSource(src *source.Source, appID string) (*Package, error) {
+	reader, f, appID, err := OpenDecrypted(src, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := wxapkg.NewArchive(reader, reader.Size())
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("解析索引失败: %w", err)
+	}
+
+	return &Package{
+		Archive:   archive,
+		File:      src.Name,
+		AppID:     appID,
+		Encrypted: reader.Encrypted(),
+		closer:    f,
+	}, nil
+}
+
+// OpenDecrypted 打开输入源并返回按需解密的明文读取器及实际使用的 AppID，不解析索引
+// 使用完毕后需要关闭返回的文件
+func OpenDecrypted(src *source.Source, appID string) (reader *decrypt.Reader, f source.File, resolved string, err error) {
+	resolved, err = resolveAppID(src, appID, false)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	f, err = src.Open()
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("打开文件失败: %v", err)
+	}
+
+	reader, err = decrypt.NewReader(f, src.Size, resolved)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, "", fmt.Errorf("解密失败: %w", err)
+	}
+	return reader, f, resolved, nil
+}
+
+// wcc 版本号所在的文件，按优先级排列
+var wccVersionSources = []string{enum.PageFrameHtml, enum.AppWxss, enum.Page_Frame, enum.PageFrame}
+
+// Summarize 汇总包信息，top 为列出的最大文件数量
+func Summarize(pkg *Package, top int) *PackageSummary {
+	names := pkg.Names()
+	wxapkgType, evidence := util.GetWxapkgTypeEvidence(names)
+	header := pkg.Header()
+
+	summary := &PackageSummary{
+		File:            pkg.File,
+		Encrypted:       pkg.Encrypted,
+		Type:            wxapkgType,
+		Evidence:        evidence,
+		FileCount:       len(names),
+		Size:            pkg.Size(),
+		IndexInfoLength: header.IndexInfoLength,
+		BodyInfoLength:  header.BodyInfoLength,
+	}
+	if pkg.Encrypted {
+		summary.AppID = pkg.AppID
+	}
+
+	// 读取 wcc 版本号
+	for _, source := range wccVersionSources {
+		content, err := pkg.ReadFile(source)
+		if err != nil {
+			continue
+		}
+		if version := util.GetWccVersionFromContent(string(content)); version != "" {
+			summary.WccVersion = version
+			break
+		}
+	}
+
+	// 按大小排序，取最大的几个文件
+	entries := Entries(pkg)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	if len(entries) > top {
+		entries = entries[:top]
+	}
+	summary.Largest = entries
+
+	return summary
+}
+
+// Entries 返回包内文件列表（按包内顺序）
+func Entries(pkg *Package) []EntryInfo {
+	files := pkg.Files()
+	entries := make([]EntryInfo, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, EntryInfo{Name: file.Name, Offset: file.Offset, Size: file.Size})
+	}
+	return entries
+}
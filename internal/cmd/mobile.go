@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	. "github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/restore"
+	"github.com/Ackites/KillWxapkg/internal/util"
+)
+
+// mobileNameRegex 移动端缓存的包名，如 _-1234567_56、<hash>_<version>，分包在标识和版本号之间带有分包标识
+var mobileNameRegex = regexp.MustCompile(`^_?(-?[0-9A-Za-z]+)(?:_-?[0-9A-Za-z]+)*_(\d+)$`)
+
+// MobileApp 同一小程序同一版本的一组包
+type MobileApp struct {
+	ID      string
+	Version string
+	Main    string   // 主包路径，未找到时为空
+	Files   []string // 全部包路径，主包在前
+}
+
+// Name 输出目录名，标识为负数时与原文件名一样以下划线开头，避免被当作命令行参数
+func (app *MobileApp) Name() string {
+	name := app.ID + "_" + app.Version
+	if strings.HasPrefix(name, "-") {
+		name = "_" + name
+	}
+	return name
+}
+
+// ParseMobileName 解析移动端包的文件名，返回小程序标识和版本号
+func ParseMobileName(file string) (id, version string, ok bool) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	match := mobileNameRegex.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// GroupMobilePackages 按小程序标识和版本号将移动端的包分组，并通过包内文件判断主包
+// 返回分组结果及无法识别文件名的文件
+func GroupMobilePackages(files []string) ([]*MobileApp, []string) {
+	groups := make(map[string]*MobileApp)
+	var unrecognized []string
+
+	for _, file := range files {
+		id, version, ok := ParseMobileName(file)
+		if !ok {
+			unrecognized = append(unrecognized, file)
+			continue
+		}
+		key := id + "_" + version
+		app, exists := groups[key]
+		if !exists {
+			app = &MobileApp{ID: id, Version: version}
+			groups[key] = app
+		}
+		app.Files = append(app.Files, file)
+	}
+
+	apps := make([]*MobileApp, 0, len(groups))
+	for _, app := range groups {
+		sort.Strings(app.Files)
+		for i, file := range app.Files {
+			if isMainPackageFile(file) {
+				app.Main = file
+				// 主包排在最前
+				copy(app.Files[1:i+1], app.Files[:i])
+				app.Files[0] = file
+				break
+			}
+		}
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name() < apps[j].Name()
+	})
+
+	return apps, unrecognized
+}
+
+// isMainPackageFile 根据包内文件列表判断是否为主包
+func isMainPackageFile(file string) bool {
+	pkg, err := OpenPackage(file, "")
+	if err != nil {
+		log.Printf("打开文件 %s 失败: %v\n", file, err)
+		return false
+	}
+	defer func(pkg *Package) {
+		err := pkg.Close()
+		if err != nil {
+			log.Printf("关闭文件 %s 失败: %v\n", file, err)
+		}
+	}(pkg)
+
+	return restore.IsMainPackage(&WxapkgInfo{WxapkgType: util.GetWxapkgType(pkg.Names())})
+}
+
+// MobileOutputDir 确定一组移动端包的输出目录
+// 未指定输出目录时，保存到输入目录下以标识和版本号命名的文件夹
+func MobileOutputDir(input, outputDir string, app *MobileApp) string {
+	if outputDir == "" {
+		outputDir = filepath.Dir(DetermineOutputDir(input, app.Name()))
+	}
+	return filepath.Join(outputDir, app.Name())
+}
+
+// DescribeMobileApp 描述一组移动端包，用于日志输出
+func DescribeMobileApp(app *MobileApp) string {
+	main := "未找到"
+	if app.Main != "" {
+		main = filepath.Base(app.Main)
+	}
+	return fmt.Sprintf("小程序 %s 版本 %s: %d 个包, 主包 %s", app.ID, app.Version, len(app.Files), main)
+}
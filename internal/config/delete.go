@@ -3,8 +3,9 @@ package config
 import (
 	"context"
 	"log"
-	"os"
 	"sync"
+
+	"github.com/Ackites/KillWxapkg/internal/sink"
 )
 
 // FileDeletionManager 用于管理需要删除的文件列表
@@ -21,17 +22,22 @@ var deleteOnce sync.Once
 // NewFileDeletionManager 创建或获取一个单例的FileDeletionManager
 func NewFileDeletionManager() *FileDeletionManager {
 	deleteOnce.Do(func() {
-		c := context.Background()
-		ctx, cancel := context.WithCancel(c)
-		deleteInstance = &FileDeletionManager{
-			files:    make(map[string]bool),
-			cancelFn: cancel,
-			ctx:      ctx,
-		}
+		deleteInstance = NewIsolatedDeletionManager()
 	})
 	return deleteInstance
 }
 
+// NewIsolatedDeletionManager 创建不与其他调用方共享的FileDeletionManager
+func NewIsolatedDeletionManager() *FileDeletionManager {
+	c := context.Background()
+	ctx, cancel := context.WithCancel(c)
+	return &FileDeletionManager{
+		files:    make(map[string]bool),
+		cancelFn: cancel,
+		ctx:      ctx,
+	}
+}
+
 // AddFile 添加文件路径到删除列表
 func (f *FileDeletionManager) AddFile(filePath string) {
 	f.mu.Lock()
@@ -41,6 +47,11 @@ func (f *FileDeletionManager) AddFile(filePath string) {
 
 // DeleteFiles 删除所有在列表中的文件
 func (f *FileDeletionManager) DeleteFiles() {
+	f.DeleteFilesIn(sink.NewDir(""))
+}
+
+// DeleteFilesIn 从输出目标中删除所有在列表中的文件
+func (f *FileDeletionManager) DeleteFilesIn(output sink.Sink) {
 	f.mu.Lock()
 	files := make([]string, 0, len(f.files))
 	for file := range f.files {
@@ -55,10 +66,10 @@ func (f *FileDeletionManager) DeleteFiles() {
 			return
 		default:
 			// 判断文件是否存在
-			if _, err := os.Stat(file); os.IsNotExist(err) {
+			if !output.Exists(file) {
 				continue
 			}
-			err := os.Remove(file)
+			err := output.Remove(file)
 			if err != nil {
 				log.Printf("删除文件 %s 失败: %v\n", file, err)
 			} else {
@@ -0,0 +1,55 @@
+package config
+
+import "github.com/Ackites/KillWxapkg/internal/sink"
+
+// Session 一次解包还原过程使用的状态
+// 默认会话使用进程内的单例，批量处理时每个小程序使用独立的会话，互不干扰
+type Session struct {
+	Config   *SharedConfigManager
+	Packages *WxapkgManager
+	Deletion *FileDeletionManager
+	Output   sink.Sink    // 输出目标，为空时直接写入磁盘
+	Merger   *sink.Merger // 将各个包合并到输出目标，为空时直接覆盖
+}
+
+// UseOutput 设置会话的输出目标及多个包写入同一路径时的处理方式
+func (s *Session) UseOutput(output sink.Sink, policy sink.ConflictPolicy) {
+	s.Output = output
+	s.Merger = sink.NewMerger(s.OutputSink(), policy)
+}
+
+// OutputMerger 返回会话的 Merger，未设置时返回直接覆盖的 Merger
+func (s *Session) OutputMerger() *sink.Merger {
+	if s.Merger == nil {
+		return sink.NewMerger(s.OutputSink(), sink.ConflictOverwrite)
+	}
+	return s.Merger
+}
+
+// OutputSink 返回会话的输出目标，未设置时直接写入磁盘
+func (s *Session) OutputSink() sink.Sink {
+	if s.Output == nil {
+		return sink.NewDir("")
+	}
+	return s.Output
+}
+
+// DefaultSession 返回使用进程内单例的会话
+func DefaultSession() *Session {
+	return &Session{
+		Config:   NewSharedConfigManager(),
+		Packages: GetWxapkgManager(),
+		Deletion: NewFileDeletionManager(),
+	}
+}
+
+// NewSession 创建独立的会话，配置从 settings 复制
+func NewSession(settings map[string]interface{}) *Session {
+	configManager := NewIsolatedConfigManager()
+	configManager.SetBulk(settings)
+	return &Session{
+		Config:   configManager,
+		Packages: NewWxapkgManager(),
+		Deletion: NewIsolatedDeletionManager(),
+	}
+}
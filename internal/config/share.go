@@ -16,13 +16,32 @@ var shareOnce sync.Once
 // NewSharedConfigManager 创建一个新的SharedConfigManager
 func NewSharedConfigManager() *SharedConfigManager {
 	shareOnce.Do(func() {
-		shareInstance = &SharedConfigManager{
-			settings: make(map[string]interface{}),
-		}
+		shareInstance = NewIsolatedConfigManager()
 	})
 	return shareInstance
 }
 
+// NewIsolatedConfigManager 创建不与其他调用方共享的配置管理器
+func NewIsolatedConfigManager() *SharedConfigManager {
+	return &SharedConfigManager{
+		settings: make(map[string]interface{}),
+	}
+}
+
+// GetBool 获取布尔类型的配置项，不存在或类型不符时返回 false
+func (scm *SharedConfigManager) GetBool(key string) bool {
+	value, _ := scm.Get(key)
+	b, _ := value.(bool)
+	return b
+}
+
+// GetInt 获取整数类型的配置项，不存在或类型不符时返回 0
+func (scm *SharedConfigManager) GetInt(key string) int {
+	value, _ := scm.Get(key)
+	i, _ := value.(int)
+	return i
+}
+
 // Set 设置一个配置项的值
 func (scm *SharedConfigManager) Set(key string, value interface{}) {
 	scm.mu.Lock()
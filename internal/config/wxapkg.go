@@ -31,6 +31,7 @@ type WxapkgInfo struct {
 
 // WxapkgManager 管理多个微信小程序包
 type WxapkgManager struct {
+	mu       sync.Mutex
 	Packages map[string]*WxapkgInfo
 }
 
@@ -40,20 +41,29 @@ var wxapkgOnce sync.Once
 // GetWxapkgManager 获取单例的 WxapkgManager 实例
 func GetWxapkgManager() *WxapkgManager {
 	wxapkgOnce.Do(func() {
-		managerInstance = &WxapkgManager{
-			Packages: make(map[string]*WxapkgInfo),
-		}
+		managerInstance = NewWxapkgManager()
 	})
 	return managerInstance
 }
 
+// NewWxapkgManager 创建独立的 WxapkgManager 实例
+func NewWxapkgManager() *WxapkgManager {
+	return &WxapkgManager{
+		Packages: make(map[string]*WxapkgInfo),
+	}
+}
+
 // AddPackage 添加包信息
 func (manager *WxapkgManager) AddPackage(id string, info *WxapkgInfo) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
 	manager.Packages[id] = info
 }
 
 // GetPackage 获取包信息
 func (manager *WxapkgManager) GetPackage(id string) (*WxapkgInfo, bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
 	info, exists := manager.Packages[id]
 	return info, exists
 }
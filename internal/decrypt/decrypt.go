@@ -7,6 +7,7 @@ import (
 	"crypto/sha1"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 
 	"golang.org/x/crypto/pbkdf2"
@@ -19,55 +20,139 @@ const (
 	defaultXorKey = 0x66
 )
 
+// DecryptWxapkg 读取并解密 wxapkg 文件
 func DecryptWxapkg(inputFile, appID string) ([]byte, error) {
 	ciphertext, err := os.ReadFile(inputFile)
 	if err != nil {
-		return nil, fmt.Errorf("读取文件失败: %v", err)
-	}
-
-	// 先检查是否已解密
-	reader := bytes.NewReader(ciphertext)
-	var firstMark byte
-	binary.Read(reader, binary.BigEndian, &firstMark)
-	var info1, indexInfoLength, bodyInfoLength uint32
-	binary.Read(reader, binary.BigEndian, &info1)
-	binary.Read(reader, binary.BigEndian, &indexInfoLength)
-	binary.Read(reader, binary.BigEndian, &bodyInfoLength)
-	var lastMark byte
-	binary.Read(reader, binary.BigEndian, &lastMark)
-	if firstMark == 0xBE && lastMark == 0xED {
-		// 已解密直接返回
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	return DecryptWxapkgData(ciphertext, appID)
+}
+
+// DecryptWxapkgData 解密内存中的 wxapkg 数据，已解密的数据原样返回
+func DecryptWxapkgData(ciphertext []byte, appID string) ([]byte, error) {
+	reader, err := NewReader(bytes.NewReader(ciphertext), int64(len(ciphertext)), appID)
+	if err != nil {
+		return nil, err
+	}
+	if !reader.Encrypted() {
 		return ciphertext, nil
 	}
 
-	if string(ciphertext[:len(fileHeader)]) != fileHeader {
-		return nil, fmt.Errorf("无效的文件格式")
+	originData := make([]byte, reader.Size())
+	if _, err := reader.ReadAt(originData, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("解密失败: %w", err)
+	}
+	return originData, nil
+}
+
+// IsEncrypted 判断数据是否为 V1MMWX 加密格式
+func IsEncrypted(data []byte) bool {
+	return len(data) >= len(fileHeader) && string(data[:len(fileHeader)]) == fileHeader
+}
+
+// VerifyAppID 解密首个加密块并校验文件头，判断 AppID 是否正确
+// head 为加密文件开头至少 22 字节的数据，size 为加密文件总长度，小于 0 时不校验长度（用于被截断的文件）
+func VerifyAppID(head []byte, size int64, appID string) error {
+	firstBlock, err := DecryptHeader(head, appID)
+	if err != nil {
+		return err
+	}
+
+	// 解密后长度：1024 字节加密段还原为 1023 字节，其余长度不变
+	plainSize := size - int64(len(fileHeader)) - 1
+	if size < 0 {
+		plainSize = -1
 	}
+	if !validHeader(firstBlock, plainSize) {
+		return fmt.Errorf("%w: %s", ErrWrongAppID, appID)
+	}
+	return nil
+}
 
-	key := pbkdf2.Key([]byte(appID), []byte(saltStr), 1000, 32, sha1.New)
+// DecryptHeader 解密首个加密块，返回 16 字节的明文文件头，首尾标记不正确时返回 ErrWrongAppID
+// head 为加密文件开头至少 22 字节的数据
+func DecryptHeader(head []byte, appID string) ([]byte, error) {
+	if !IsEncrypted(head) {
+		return nil, ErrNotEncrypted
+	}
+	if len(head) < len(fileHeader)+aes.BlockSize {
+		return nil, fmt.Errorf("%w: 文件头长度 %d", ErrTruncated, len(head))
+	}
 
-	block, err := aes.NewCipher(key)
+	block, err := aes.NewCipher(deriveKey(appID))
 	if err != nil {
 		return nil, fmt.Errorf("创建AES密码块失败: %v", err)
 	}
 
-	iv := []byte(ivStr)
-	mode := cipher.NewCBCDecrypter(block, iv)
-	originData := make([]byte, 1024)
-	mode.CryptBlocks(originData, ciphertext[6:1024+6])
+	firstBlock := make([]byte, aes.BlockSize)
+	cipher.NewCBCDecrypter(block, []byte(ivStr)).CryptBlocks(firstBlock, head[len(fileHeader):len(fileHeader)+aes.BlockSize])
 
-	afData := make([]byte, len(ciphertext)-1024-6)
-	var xorKey byte
-	if len(appID) >= 2 {
-		xorKey = appID[len(appID)-2]
-	} else {
-		xorKey = defaultXorKey
+	if !validHeader(firstBlock, -1) {
+		return nil, fmt.Errorf("%w: %s", ErrWrongAppID, appID)
 	}
-	for i, b := range ciphertext[1024+6:] {
-		afData[i] = b ^ xorKey
+	return firstBlock, nil
+}
+
+// EncryptedSize 根据明文长度计算加密后的文件长度
+func EncryptedSize(plainSize int64) int64 {
+	if plainSize > 1023 {
+		// 前 1023 字节填充为 1024 字节的 AES 加密段，其余部分异或后长度不变
+		return int64(len(fileHeader)) + plainSize + 1
+	}
+	return int64(len(fileHeader)) + (plainSize/aes.BlockSize+1)*aes.BlockSize
+}
+
+// FindAppID 依次尝试候选 AppID，返回第一个能正确解密的 AppID
+func FindAppID(head []byte, size int64, candidates []string) (string, error) {
+	for _, appID := range candidates {
+		if VerifyAppID(head, size, appID) == nil {
+			return appID, nil
+		}
 	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: 未指定AppID且无法从路径推断", ErrWrongAppID)
+	}
+	return "", fmt.Errorf("%w: 已尝试 %v", ErrWrongAppID, candidates)
+}
 
-	originData = append(originData[:1023], afData...)
+// validHeader 校验明文文件头的首尾标记及长度
+func validHeader(plain []byte, size int64) bool {
+	if len(plain) < 14 || plain[0] != 0xBE || plain[13] != 0xED {
+		return false
+	}
+	indexInfoLength := binary.BigEndian.Uint32(plain[5:9])
+	bodyInfoLength := binary.BigEndian.Uint32(plain[9:13])
+	return size < 0 || uint64(indexInfoLength)+uint64(bodyInfoLength) <= uint64(size)
+}
 
-	return originData, nil
+// deriveKey 根据 AppID 生成 AES 密钥
+func deriveKey(appID string) []byte {
+	return pbkdf2.Key([]byte(appID), []byte(saltStr), 1000, 32, sha1.New)
+}
+
+// xorKeyOf 获取异或段使用的密钥字节
+func xorKeyOf(appID string) byte {
+	if len(appID) >= 2 {
+		return appID[len(appID)-2]
+	}
+	return defaultXorKey
+}
+
+// pkcs7Unpad 去除 PKCS7 填充
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("填充数据为空")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, fmt.Errorf("无效的填充长度: %d", n)
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, fmt.Errorf("无效的填充内容")
+		}
+	}
+	return data[:len(data)-n], nil
 }
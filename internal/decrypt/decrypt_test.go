@@ -0,0 +1,145 @@
+package decrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testAppID = "wx0123456789abcdef"
+
+// plainPackage 生成长度为 size 的明文包，没有文件，其余数据均属于数据段
+func plainPackage(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + 3)
+	}
+	data[0] = 0xBE
+	binary.BigEndian.PutUint32(data[1:5], 0)
+	binary.BigEndian.PutUint32(data[5:9], 4)
+	binary.BigEndian.PutUint32(data[9:13], uint32(size-18))
+	data[13] = 0xED
+	binary.BigEndian.PutUint32(data[14:18], 0)
+	return data
+}
+
+// FuzzDecryptWxapkg 任意输入都不应导致 panic
+func FuzzDecryptWxapkg(f *testing.F) {
+	for _, size := range []int{18, 1023, 1024, 2048} {
+		encrypted, err := EncryptWxapkg(plainPackage(size), testAppID)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(encrypted)
+		f.Add(encrypted[:len(encrypted)-1])
+	}
+	f.Add(plainPackage(18))
+	f.Add([]byte(fileHeader))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DecryptWxapkgData(data, testAppID)
+		_ = VerifyAppID(data, int64(len(data)), testAppID)
+
+		reader, err := NewSalvageReader(bytes.NewReader(data), int64(len(data)), testAppID)
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 64)
+		for off := int64(0); off < reader.Size(); off += 509 {
+			_, _ = reader.ReadAt(buf, off)
+		}
+	})
+}
+
+// 最后一个字节或倒数第二个字节（异或密钥）不同的 AppID，以及使用默认异或密钥的短 AppID
+var roundTripAppIDs = []string{
+	"wx0123456789abcdef",
+	"wx0123456789abcdeg",
+	"wx0123456789abcdxf",
+	"x",
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	sizes := []int{1, 17, 18, 1022, 1023, 1024, 1025, 1<<20 + 3}
+	for i, appID := range roundTripAppIDs {
+		other := roundTripAppIDs[(i+1)%len(roundTripAppIDs)]
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s/%d", appID, size), func(t *testing.T) {
+				plain := plainPackage(max(size, 18))[:size]
+				encrypted, err := EncryptWxapkg(plain, appID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !IsEncrypted(encrypted) {
+					t.Fatal("加密结果缺少 V1MMWX 文件头")
+				}
+				if got, want := int64(len(encrypted)), EncryptedSize(int64(size)); got != want {
+					t.Fatalf("密文长度 %d, 期望 %d", got, want)
+				}
+
+				// 不足一个文件头的数据无法通过文件头校验，直接解密加密段比对
+				if size < 18 {
+					if got := decryptSection(t, encrypted, appID); !bytes.Equal(got, plain) {
+						t.Fatalf("加密段解密结果 %x, 期望 %x", got, plain)
+					}
+					return
+				}
+
+				decrypted, err := DecryptWxapkgData(encrypted, appID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(decrypted, plain) {
+					t.Fatal("解密结果与原数据不一致")
+				}
+
+				file := filepath.Join(t.TempDir(), "test.wxapkg")
+				if err := os.WriteFile(file, encrypted, 0644); err != nil {
+					t.Fatal(err)
+				}
+				decrypted, err = DecryptWxapkg(file, appID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(decrypted, plain) {
+					t.Fatal("从文件解密的结果与原数据不一致")
+				}
+
+				if _, err := DecryptWxapkgData(encrypted, other); !errors.Is(err, ErrWrongAppID) {
+					t.Fatalf("使用 AppID %s 解密: %v, 期望 ErrWrongAppID", other, err)
+				}
+			})
+		}
+	}
+}
+
+func TestEncryptEmpty(t *testing.T) {
+	if _, err := EncryptWxapkg(nil, testAppID); err == nil {
+		t.Fatal("加密空数据应返回错误")
+	}
+	if _, err := EncryptWxapkg(plainPackage(18), ""); err == nil {
+		t.Fatal("未指定 AppID 时应返回错误")
+	}
+}
+
+// decryptSection 解密 AES 加密段并去除填充
+func decryptSection(t *testing.T, encrypted []byte, appID string) []byte {
+	block, err := aes.NewCipher(deriveKey(appID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := make([]byte, len(encrypted)-len(fileHeader))
+	cipher.NewCBCDecrypter(block, []byte(ivStr)).CryptBlocks(section, encrypted[len(fileHeader):])
+	plain, err := pkcs7Unpad(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
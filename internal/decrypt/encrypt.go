@@ -0,0 +1,49 @@
+package decrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// EncryptWxapkg 将明文 wxapkg 数据加密为 PC 端使用的 V1MMWX 格式
+// 前 1023 字节经 PKCS7 填充后使用 AES-CBC 加密，其余部分与 AppID 派生的字节异或
+func EncryptWxapkg(data []byte, appID string) ([]byte, error) {
+	if appID == "" {
+		return nil, fmt.Errorf("加密需要指定AppID")
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("待加密数据为空")
+	}
+
+	block, err := aes.NewCipher(deriveKey(appID))
+	if err != nil {
+		return nil, fmt.Errorf("创建AES密码块失败: %v", err)
+	}
+
+	headLen := min(len(data), 1023)
+	head := pkcs7Pad(data[:headLen])
+
+	ciphertext := make([]byte, len(fileHeader)+len(head)+len(data)-headLen)
+	copy(ciphertext, fileHeader)
+	cipher.NewCBCEncrypter(block, []byte(ivStr)).CryptBlocks(ciphertext[len(fileHeader):], head)
+
+	xorKey := xorKeyOf(appID)
+	tail := ciphertext[len(fileHeader)+len(head):]
+	for i, b := range data[headLen:] {
+		tail[i] = b ^ xorKey
+	}
+
+	return ciphertext, nil
+}
+
+// pkcs7Pad 按 AES 块大小进行 PKCS7 填充
+func pkcs7Pad(data []byte) []byte {
+	n := aes.BlockSize - len(data)%aes.BlockSize
+	padded := make([]byte, len(data)+n)
+	copy(padded, data)
+	for i := len(data); i < len(padded); i++ {
+		padded[i] = byte(n)
+	}
+	return padded
+}
@@ -0,0 +1,18 @@
+package decrypt
+
+import (
+	"errors"
+
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
+)
+
+var (
+	// ErrTruncated 文件长度不足，数据被截断
+	ErrTruncated = wxapkg.ErrTruncated
+	// ErrBadMagic 文件头标记不正确，既不是 V1MMWX 加密格式也不是明文 wxapkg
+	ErrBadMagic = wxapkg.ErrBadMagic
+	// ErrNotEncrypted 文件不是 V1MMWX 加密格式
+	ErrNotEncrypted = errors.New("文件未加密")
+	// ErrWrongAppID AppID 与加密包不匹配
+	ErrWrongAppID = errors.New("AppID错误，解密后的文件头校验失败")
+)
@@ -0,0 +1,152 @@
+package decrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+)
+
+// Reader 按需解密 wxapkg 的 io.ReaderAt 实现
+// 仅在创建时解密首个 1024 字节的 AES 加密段，其余部分在读取时逐字节异或，不会在内存中生成完整明文
+type Reader struct {
+	r      io.ReaderAt
+	size   int64  // 明文长度
+	head   []byte // 已解密的 AES 加密段，明文包时为空
+	offset int64  // 异或段在密文中的起始位置
+	xorKey byte
+}
+
+// NewReader 创建解密读取器，size 为底层数据的总长度
+// 未加密的包会直接透传读取，AppID 错误时返回 ErrWrongAppID
+func NewReader(r io.ReaderAt, size int64, appID string) (*Reader, error) {
+	return newReader(r, size, appID, false)
+}
+
+// NewSalvageReader 创建用于被截断文件的解密读取器
+// 加密段不完整时只解密完整的 AES 块，文件头仅校验首尾标记，不校验长度
+func NewSalvageReader(r io.ReaderAt, size int64, appID string) (*Reader, error) {
+	return newReader(r, size, appID, true)
+}
+
+func newReader(r io.ReaderAt, size int64, appID string, salvage bool) (*Reader, error) {
+	prefix := make([]byte, 14)
+	n, err := r.ReadAt(prefix, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	prefix = prefix[:n]
+
+	// 已解密的包直接透传
+	if len(prefix) == 14 && prefix[0] == 0xBE && prefix[13] == 0xED {
+		return &Reader{r: r, size: size}, nil
+	}
+
+	if !IsEncrypted(prefix) {
+		if len(prefix) < 14 {
+			return nil, fmt.Errorf("%w: 文件长度 %d", ErrTruncated, size)
+		}
+		return nil, fmt.Errorf("无效的文件格式: %w", ErrBadMagic)
+	}
+
+	headLen := min(size-int64(len(fileHeader)), 1024)
+	if salvage {
+		headLen -= headLen % aes.BlockSize
+	}
+	if headLen <= 0 || headLen%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: 加密段长度 %d 不合法", ErrTruncated, headLen)
+	}
+
+	ciphertext := make([]byte, headLen)
+	if _, err := r.ReadAt(ciphertext, int64(len(fileHeader))); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("读取加密段失败: %w", err)
+	}
+
+	block, err := aes.NewCipher(deriveKey(appID))
+	if err != nil {
+		return nil, fmt.Errorf("创建AES密码块失败: %v", err)
+	}
+	head := make([]byte, headLen)
+	cipher.NewCBCDecrypter(block, []byte(ivStr)).CryptBlocks(head, ciphertext)
+
+	offset := int64(len(fileHeader)) + headLen
+	if salvage && headLen < 1024 {
+		// 加密段不完整，末尾不足一个 AES 块的数据无法解密
+		size = offset
+	}
+	if size > offset {
+		// 存在异或段时，加密段只保留前 1023 字节
+		head = head[:1023]
+	} else {
+		// 没有异或段时，加密段长度可能不足 1024 字节，需按 PKCS7 去除填充
+		unpadded, err := pkcs7Unpad(head)
+		switch {
+		case err == nil:
+			head = unpadded
+		case !salvage:
+			return nil, fmt.Errorf("%w: %s", ErrWrongAppID, appID)
+		case len(head) > 1023:
+			// 被截断的文件无法确定填充，只保留确定为明文的部分
+			head = head[:1023]
+		}
+	}
+
+	plainSize := int64(len(head)) + size - offset
+	checkSize := plainSize
+	if salvage {
+		checkSize = -1
+	}
+	if !validHeader(head, checkSize) {
+		return nil, fmt.Errorf("%w: %s", ErrWrongAppID, appID)
+	}
+
+	return &Reader{
+		r:      r,
+		size:   plainSize,
+		head:   head,
+		offset: offset,
+		xorKey: xorKeyOf(appID),
+	}, nil
+}
+
+// Size 返回明文长度
+func (d *Reader) Size() int64 {
+	return d.size
+}
+
+// Encrypted 是否为加密包
+func (d *Reader) Encrypted() bool {
+	return d.head != nil
+}
+
+// ReadAt 读取指定位置的明文数据
+func (d *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("无效的偏移量: %d", off)
+	}
+	if off >= d.size {
+		return 0, io.EOF
+	}
+	if d.head == nil {
+		return d.r.ReadAt(p, off)
+	}
+
+	n := 0
+	headLen := int64(len(d.head))
+
+	// AES 加密段已在内存中
+	if off < headLen {
+		n = copy(p, d.head[off:])
+		off += int64(n)
+	}
+	if n == len(p) {
+		return n, nil
+	}
+
+	// 异或段按需读取
+	m, err := d.r.ReadAt(p[n:], off-headLen+d.offset)
+	for i := n; i < n+m; i++ {
+		p[i] ^= d.xorKey
+	}
+	return n + m, err
+}
@@ -17,6 +17,10 @@ const (
 	SubContext    = "subContext.js"   // 子上下文脚本文件
 	Plugin        = "plugin.js"       // 插件脚本文件
 	PluginJson    = "plugin.json"     // 插件JSON文件
+
+	OpenDataContext = "openDataContext" // 小游戏开放数据域的默认目录
+	PluginRoot      = "plugin"          // 插件工程中插件代码所在目录
+	MiniprogramRoot = "miniprogram"     // 插件工程中用于调试插件的小程序所在目录
 )
 
 // WxapkgType 定义微信小程序包的类型
@@ -20,13 +20,12 @@ func RegisterFormatter(ext string, formatter Formatter) {
 	formatters[strings.ToLower(ext)] = formatter
 }
 
-// GetFormatter 返回文件扩展名对应的格式化器
-func GetFormatter(ext string) (Formatter, error) {
+// GetFormatter 按会话的配置返回文件扩展名对应的格式化器
+func GetFormatter(configManager *SharedConfigManager, ext string) (Formatter, error) {
 	formatter, exists := formatters[strings.ToLower(ext)]
 	if !exists {
 		return nil, fmt.Errorf("不支持的文件类型: %s", ext)
 	}
-	configManager := NewSharedConfigManager()
 	if pretty, ok := configManager.Get("pretty"); ok {
 		if p, o := pretty.(bool); o {
 			if !p && ext == ".js" {
@@ -1,6 +1,7 @@
 package pack
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -9,10 +10,13 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Ackites/KillWxapkg/internal/decrypt"
+
 	"github.com/fsnotify/fsnotify"
 )
 
-func Repack(path string, watch bool, outputDir string) {
+// Repack 重新打包目录，指定 appID 时输出 V1MMWX 加密格式
+func Repack(path string, watch bool, outputDir string, appID string) {
 	// 过滤空白字符
 	path = strings.TrimSpace(path)
 	outputDir = strings.TrimSpace(outputDir)
@@ -24,14 +28,14 @@ func Repack(path string, watch bool, outputDir string) {
 	}
 
 	// 打包目录
-	err := packWxapkg(path, outputDir)
+	err := packWxapkg(path, outputDir, appID)
 	if err != nil {
 		log.Printf("错误: %v\n", err)
 		return
 	}
 
 	if watch {
-		watchDir(path, outputDir)
+		watchDir(path, outputDir, appID)
 	}
 
 	return
@@ -45,7 +49,7 @@ type WxapkgFile struct {
 }
 
 // 打包文件到 wxapkg 格式
-func packWxapkg(inputDir string, outputDir string) error {
+func packWxapkg(inputDir string, outputDir string, appID string) error {
 	var files []WxapkgFile
 	var totalSize uint32
 
@@ -121,54 +125,62 @@ func packWxapkg(inputDir string, outputDir string) error {
 		}
 	}(outFile)
 
+	// 需要加密时先写入缓冲区，最后统一加密
+	var out io.Writer = outFile
+	var plain *bytes.Buffer
+	if appID != "" {
+		plain = new(bytes.Buffer)
+		out = plain
+	}
+
 	// 写入文件头
-	if err := binary.Write(outFile, binary.BigEndian, byte(0xBE)); err != nil {
+	if err := binary.Write(out, binary.BigEndian, byte(0xBE)); err != nil {
 		return fmt.Errorf("写入文件头标记失败: %w", err)
 	}
 
 	info1 := uint32(0) // 示例值
-	if err := binary.Write(outFile, binary.BigEndian, info1); err != nil {
+	if err := binary.Write(out, binary.BigEndian, info1); err != nil {
 		return fmt.Errorf("写入 info1 失败: %w", err)
 	}
 
-	// 计算索引段长度，包含每个文件的元数据长度和文件名长度
-	var indexInfoLength uint32
+	// 计算索引段长度，包含文件数量及每个文件的元数据长度和文件名长度
+	indexInfoLength := uint32(4) // FileCount
 	for _, file := range files {
 		indexInfoLength += 4 + uint32(len(file.Name)) + 4 + 4 // NameLen + Name + Offset + Size
 	}
 
-	if err := binary.Write(outFile, binary.BigEndian, indexInfoLength); err != nil {
+	if err := binary.Write(out, binary.BigEndian, indexInfoLength); err != nil {
 		return fmt.Errorf("写入索引段长度失败: %w", err)
 	}
 
 	bodyInfoLength := totalSize
-	if err := binary.Write(outFile, binary.BigEndian, bodyInfoLength); err != nil {
+	if err := binary.Write(out, binary.BigEndian, bodyInfoLength); err != nil {
 		return fmt.Errorf("写入数据段长度失败: %w", err)
 	}
 
-	if err := binary.Write(outFile, binary.BigEndian, byte(0xED)); err != nil {
+	if err := binary.Write(out, binary.BigEndian, byte(0xED)); err != nil {
 		return fmt.Errorf("写入文件尾标记失败: %w", err)
 	}
 
 	// 写入文件数量
 	fileCount := uint32(len(files))
-	if err := binary.Write(outFile, binary.BigEndian, fileCount); err != nil {
+	if err := binary.Write(out, binary.BigEndian, fileCount); err != nil {
 		return fmt.Errorf("写入文件数量失败: %w", err)
 	}
 
 	// 写入索引段
 	for _, file := range files {
-		if err := binary.Write(outFile, binary.BigEndian, file.NameLen); err != nil {
+		if err := binary.Write(out, binary.BigEndian, file.NameLen); err != nil {
 			return fmt.Errorf("写入文件名长度失败: %w", err)
 		}
-		if _, err := outFile.Write([]byte(file.Name)); err != nil {
+		if _, err := out.Write([]byte(file.Name)); err != nil {
 			return fmt.Errorf("写入文件名失败: %w", err)
 		}
-		// 加上 18 字节文件头长度和索引段长度
-		if err := binary.Write(outFile, binary.BigEndian, file.Offset+indexInfoLength+18); err != nil {
+		// 加上 14 字节文件头长度和索引段长度
+		if err := binary.Write(out, binary.BigEndian, file.Offset+indexInfoLength+14); err != nil {
 			return fmt.Errorf("写入文件偏移量失败: %w", err)
 		}
-		if err := binary.Write(outFile, binary.BigEndian, file.Size); err != nil {
+		if err := binary.Write(out, binary.BigEndian, file.Size); err != nil {
 			return fmt.Errorf("写入文件大小失败: %w", err)
 		}
 	}
@@ -188,16 +200,43 @@ func packWxapkg(inputDir string, outputDir string) error {
 				}
 			}(f)
 
-			if _, err = io.Copy(outFile, f); err != nil {
+			if _, err = io.Copy(out, f); err != nil {
 				log.Printf("写入文件内容失败: %v\n", err)
 			}
 		}()
 	}
 
+	if plain != nil {
+		return writeEncrypted(outFile, plain.Bytes(), appID)
+	}
+
+	return nil
+}
+
+// writeEncrypted 加密并写入输出文件，写入前校验能否被正确解密
+func writeEncrypted(w io.Writer, data []byte, appID string) error {
+	encrypted, err := decrypt.EncryptWxapkg(data, appID)
+	if err != nil {
+		return fmt.Errorf("加密失败: %w", err)
+	}
+
+	decrypted, err := decrypt.DecryptWxapkgData(encrypted, appID)
+	if err != nil {
+		return fmt.Errorf("加密结果校验失败: %w", err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		return fmt.Errorf("加密结果校验失败: 解密后内容不一致")
+	}
+
+	if _, err := w.Write(encrypted); err != nil {
+		return fmt.Errorf("写入加密文件失败: %w", err)
+	}
+
+	log.Printf("已使用AppID %s 加密输出文件\n", appID)
 	return nil
 }
 
-func watchDir(inputDir string, outputDir string) {
+func watchDir(inputDir string, outputDir string, appID string) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Println("ERROR: ", err)
@@ -217,7 +256,7 @@ func watchDir(inputDir string, outputDir string) {
 			case event := <-watcher.Events:
 				if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Remove == fsnotify.Remove {
 					log.Println("检测到文件变化: ", event.Name)
-					if err := packWxapkg(inputDir, outputDir); err != nil {
+					if err := packWxapkg(inputDir, outputDir, appID); err != nil {
 						log.Println("打包失败: ", err)
 					} else {
 						log.Println("打包成功")
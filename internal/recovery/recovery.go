@@ -0,0 +1,323 @@
+package recovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
+)
+
+const (
+	// 加密包的文件头前缀
+	encryptedMark = "V1MMWX"
+	// AES 加密段的长度（密文）
+	aesSegmentLen = 1024
+	// AES 加密段解密后保留的明文长度，异或段从明文的该位置开始
+	plainHeadLen = 1023
+	// 用于猜测异或密钥的样本长度
+	sampleLen = 64 << 10
+)
+
+// IndexUnreadable 报告中说明 AES 加密段无法读取的提示
+const IndexUnreadable = "文件头及索引位于AES加密的首个数据块中，缺少AppID无法读取，仅恢复了异或段中的内容"
+
+// File 恢复出的文件
+type File struct {
+	Name      string `json:"name"`      // 索引中的文件名，或根据偏移量生成的文件名
+	Synthetic bool   `json:"synthetic"` // 文件名是否为生成的
+	Offset    int64  `json:"offset"`    // 在明文包中的偏移量
+	Size      int64  `json:"size"`      // 恢复出的长度
+	Complete  bool   `json:"complete"`  // 是否已按索引确认内容完整
+	Content   []byte `json:"-"`
+}
+
+// Result 恢复结果
+type Result struct {
+	XorKey        string  `json:"xorKey"`        // 猜测的异或密钥（十六进制）
+	Score         float64 `json:"score"`         // 密钥评分，即解码后内容看起来像文本的程度
+	IndexReadable bool    `json:"indexReadable"` // AES 加密的首个数据块是否可读，始终为 false
+	IndexEntries  int     `json:"indexEntries"`  // 从异或段中恢复的索引项数量
+	Note          string  `json:"note"`
+	Files         []File  `json:"files"`
+}
+
+// Recover 在缺少 AppID 时尽可能恢复加密包的内容
+// 异或段使用单字节密钥，依次尝试 256 个密钥并按内容评分选出最可能的密钥，
+// 再从解码后的数据中恢复延伸到异或段的索引项，其余内容按文件特征切分并生成文件名
+func Recover(data []byte) (*Result, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedMark)) {
+		return nil, fmt.Errorf("文件不是V1MMWX加密格式")
+	}
+	if len(data) <= len(encryptedMark)+aesSegmentLen {
+		return nil, fmt.Errorf("文件仅包含AES加密段，缺少AppID无法恢复")
+	}
+
+	region := data[len(encryptedMark)+aesSegmentLen:]
+	key, score := GuessXorKey(region[:min(len(region), sampleLen)])
+
+	plain := make([]byte, len(region))
+	for i, b := range region {
+		plain[i] = b ^ key
+	}
+
+	result := &Result{
+		XorKey: fmt.Sprintf("0x%02x", key),
+		Score:  score,
+		Note:   IndexUnreadable,
+	}
+
+	// 明文中的偏移量 = plainHeadLen + 在异或段中的位置
+	plainSize := int64(plainHeadLen + len(plain))
+	entries, indexEnd := recoverIndex(plain, plainSize)
+	result.IndexEntries = len(entries)
+
+	if len(entries) == 0 {
+		result.Files = carve(plain, plainHeadLen)
+		return result, nil
+	}
+
+	// 索引中的文件，起始位置在 AES 加密段中的只能恢复其尾部
+	first := plainSize
+	for _, entry := range entries {
+		start := max(int64(entry.Offset), plainHeadLen)
+		end := int64(entry.Offset) + int64(entry.Size)
+		first = min(first, int64(entry.Offset))
+		if end <= start {
+			// 文件完全位于 AES 加密段中
+			continue
+		}
+		file := File{
+			Name:     entry.Name,
+			Offset:   start,
+			Size:     end - start,
+			Complete: start == int64(entry.Offset),
+			Content:  plain[start-plainHeadLen : end-plainHeadLen],
+		}
+		result.Files = append(result.Files, file)
+	}
+
+	// 索引段之后、第一个已知文件之前的数据属于索引项丢失的文件
+	if bodyStart := plainHeadLen + int64(indexEnd); first > bodyStart {
+		result.Files = append(carve(plain[indexEnd:first-plainHeadLen], bodyStart), result.Files...)
+	}
+
+	return result, nil
+}
+
+// GuessXorKey 尝试全部 256 个异或密钥，返回解码后最像文本的密钥及其评分
+func GuessXorKey(sample []byte) (byte, float64) {
+	var bestKey byte
+	bestScore := -1.0
+	decoded := make([]byte, len(sample))
+	for k := 0; k < 256; k++ {
+		for i, b := range sample {
+			decoded[i] = b ^ byte(k)
+		}
+		if score := textScore(decoded); score > bestScore {
+			bestKey, bestScore = byte(k), score
+		}
+	}
+	return bestKey, bestScore
+}
+
+// JS/JSON 中常见的片段，用于区分评分接近的密钥
+var commonTokens = [][]byte{
+	[]byte("function"), []byte("return"), []byte("var "), []byte("this."), []byte("\":"), []byte("=="), []byte("();"),
+}
+
+// textScore 评估数据看起来像 JS/JSON 文本的程度，范围约为 0~2
+func textScore(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var printable float64
+	for _, b := range data {
+		switch {
+		case b >= 0x20 && b < 0x7F, b == '\n', b == '\r', b == '\t':
+			printable++
+		case b >= 0x80:
+			// 中文等多字节 UTF-8 字符
+			printable += 0.5
+		}
+	}
+	score := printable / float64(len(data))
+
+	var tokens int
+	for _, token := range commonTokens {
+		tokens += bytes.Count(data, token) * len(token)
+	}
+	return score + min(float64(tokens)/float64(len(data)), 1)
+}
+
+// recoverIndex 从异或段开头查找延伸到该段的索引项
+// 返回恢复出的索引项，以及索引段在异或段中的结束位置
+func recoverIndex(plain []byte, plainSize int64) ([]wxapkg.WxapkgFile, int) {
+	var best []wxapkg.WxapkgFile
+	bestEnd := 0
+
+	// 完整的索引项最长为 4 + MaxNameLen + 8 字节，第一个完整的索引项必然从此范围内开始
+	limit := min(len(plain), 4+wxapkg.MaxNameLen+8)
+	for start := 0; start < limit; start++ {
+		entries, end := parseEntries(plain, start, plainSize)
+		if len(entries) == 0 {
+			continue
+		}
+
+		// 索引段之后紧接着数据段，第一个文件应从索引段结束处开始
+		consistent := false
+		for _, entry := range entries {
+			if int64(entry.Offset) == plainHeadLen+int64(end) {
+				consistent = true
+				break
+			}
+		}
+		if !consistent && len(entries) < 2 {
+			continue
+		}
+		if len(entries) > len(best) {
+			best, bestEnd = entries, end
+		}
+	}
+
+	return best, bestEnd
+}
+
+// parseEntries 从 start 开始连续解析索引项，遇到不合理的数据时停止
+func parseEntries(plain []byte, start int, plainSize int64) ([]wxapkg.WxapkgFile, int) {
+	var entries []wxapkg.WxapkgFile
+	pos := start
+	for pos+4 <= len(plain) {
+		nameLen := binary.BigEndian.Uint32(plain[pos:])
+		if nameLen < 2 || nameLen > wxapkg.MaxNameLen || pos+4+int(nameLen)+8 > len(plain) {
+			break
+		}
+		name := plain[pos+4 : pos+4+int(nameLen)]
+		if !plausibleName(name) {
+			break
+		}
+		offset := binary.BigEndian.Uint32(plain[pos+4+int(nameLen):])
+		size := binary.BigEndian.Uint32(plain[pos+8+int(nameLen):])
+		if offset < wxapkg.HeaderSize || int64(offset)+int64(size) > plainSize {
+			break
+		}
+		entries = append(entries, wxapkg.WxapkgFile{NameLen: nameLen, Name: string(name), Offset: offset, Size: size})
+		pos += 12 + int(nameLen)
+	}
+	return entries, pos
+}
+
+// plausibleName 包内文件名以 / 开头且为可打印的 UTF-8 文本
+func plausibleName(name []byte) bool {
+	if name[0] != '/' || !utf8.Valid(name) {
+		return false
+	}
+	for _, b := range name {
+		if b < 0x20 || b == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+// 数据中可识别的文件起始特征
+var signatures = []struct {
+	magic []byte
+	ext   string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), ".png"},
+	{[]byte("\xff\xd8\xff"), ".jpg"},
+	{[]byte("GIF87a"), ".gif"},
+	{[]byte("GIF89a"), ".gif"},
+	{[]byte("<!DOCTYPE"), ".html"},
+	{[]byte("<html"), ".html"},
+}
+
+// carve 按文件特征将数据切分为多个文件，base 为数据在明文包中的偏移量
+// 图片按格式确定结束位置，文本在相邻 JSON 对象之间及 HTML 文档开头处切分
+func carve(data []byte, base int64) []File {
+	boundaries := map[int]bool{0: true, len(data): true}
+	for i := 0; i < len(data); i++ {
+		for _, sig := range signatures {
+			if bytes.HasPrefix(data[i:], sig.magic) {
+				boundaries[i] = true
+				if end := imageEnd(data[i:], sig.ext); end > 0 {
+					boundaries[i+end] = true
+				}
+			}
+		}
+		// 文件内容直接相连，JSON 对象结束后紧接另一个 JSON 对象时视为两个文件
+		if data[i] == '}' && bytes.HasPrefix(data[i+1:], []byte("{\"")) {
+			boundaries[i+1] = true
+		}
+	}
+
+	offsets := make([]int, 0, len(boundaries))
+	for offset := range boundaries {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+
+	var files []File
+	for i := 0; i+1 < len(offsets); i++ {
+		content := data[offsets[i]:offsets[i+1]]
+		if len(content) == 0 {
+			continue
+		}
+		offset := base + int64(offsets[i])
+		files = append(files, File{
+			Name:      fmt.Sprintf("recovered_0x%x%s", offset, sniffExt(content)),
+			Synthetic: true,
+			Offset:    offset,
+			Size:      int64(len(content)),
+			Content:   content,
+		})
+	}
+	return files
+}
+
+// imageEnd 返回图片数据的结束位置，无法确定时返回 0
+func imageEnd(data []byte, ext string) int {
+	switch ext {
+	case ".png":
+		// 逐个读取数据块直到 IEND
+		pos := 8
+		for pos+12 <= len(data) {
+			length := int(binary.BigEndian.Uint32(data[pos:]))
+			if length < 0 || pos+12+length > len(data) {
+				return 0
+			}
+			chunkType := string(data[pos+4 : pos+8])
+			pos += 12 + length
+			if chunkType == "IEND" {
+				return pos
+			}
+		}
+	case ".jpg":
+		if end := bytes.Index(data, []byte("\xff\xd9")); end > 0 {
+			return end + 2
+		}
+	}
+	return 0
+}
+
+// sniffExt 根据内容推断文件扩展名
+func sniffExt(content []byte) string {
+	for _, sig := range signatures {
+		if bytes.HasPrefix(content, sig.magic) {
+			return sig.ext
+		}
+	}
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return ".json"
+	}
+	if textScore(content[:min(len(content), sampleLen)]) >= 0.9 {
+		return ".js"
+	}
+	return ".bin"
+}
@@ -13,7 +13,10 @@ import (
 	"github.com/Ackites/KillWxapkg/internal/enum"
 )
 
+// WxapkgDecompiler 根据包类型为每个包配置解析器
 type WxapkgDecompiler struct {
+	OutputDir string          // 输出目录
+	Session   *config.Session // 包信息及待删除文件所在的会话
 }
 
 func isParserV1(wxapkg *config.WxapkgInfo) bool {
@@ -75,19 +78,13 @@ func isGamePlugin(wxapkg *config.WxapkgInfo) bool {
 	}
 }
 
-// 是否插件
-func isPlugin(wxapkg *config.WxapkgInfo) bool {
+// IsPlugin 是否插件
+func IsPlugin(wxapkg *config.WxapkgInfo) bool {
 	return isAppPlugin(wxapkg) || isGamePlugin(wxapkg)
 }
 
-// OutputDir 输出目录
-var OutputDir string
-
-func (d *WxapkgDecompiler) Decompile(outputDir string) {
-	// 设置输出目录
-	OutputDir = outputDir
-
-	wxapkgManager := config.GetWxapkgManager()
+func (d *WxapkgDecompiler) Decompile() {
+	wxapkgManager := d.Session.Packages
 	for _, wxapkg := range wxapkgManager.Packages {
 		log.Println(wxapkg.WxapkgType)
 		switch wxapkg.WxapkgType {
@@ -96,33 +93,49 @@ func (d *WxapkgDecompiler) Decompile(outputDir string) {
 				ViewSource:   filepath.Join(wxapkg.SourcePath, enum.PageFrameHtml),
 				SetAppConfig: true,
 			}
-			setApp(wxapkg)
+			d.setApp(wxapkg)
 		case enum.App_V2, enum.App_V3:
 			wxapkg.Option = &config.WxapkgOption{
 				SetAppConfig: true,
 			}
-			setApp(wxapkg)
+			d.setApp(wxapkg)
 		case enum.APP_SUBPACKAGE_V1, enum.APP_SUBPACKAGE_V2:
 			wxapkg.Option = &config.WxapkgOption{
 				ViewSource:   filepath.Join(wxapkg.SourcePath, enum.Page_Frame),
 				SetAppConfig: false,
 			}
-			setApp(wxapkg)
+			d.setApp(wxapkg)
 		case enum.APP_PLUGIN_V1:
 			wxapkg.Option = &config.WxapkgOption{
 				ViewSource:    filepath.Join(wxapkg.SourcePath, enum.PageFrame),
 				ServiceSource: filepath.Join(wxapkg.SourcePath, enum.AppService),
 				SetAppConfig:  false,
 			}
-			setApp(wxapkg)
+			d.setApp(wxapkg)
 		case enum.GAME:
+			wxapkg.Option = &config.WxapkgOption{
+				ServiceSource:   filepath.Join(wxapkg.SourcePath, enum.Game),
+				AppConfigSource: filepath.Join(wxapkg.SourcePath, enum.App_Config),
+				SetAppConfig:    true,
+			}
+			d.setGame(wxapkg)
 		case enum.GAME_SUBPACKAGE:
+			wxapkg.Option = &config.WxapkgOption{
+				ServiceSource: filepath.Join(wxapkg.SourcePath, enum.Game),
+				SetAppConfig:  false,
+			}
+			d.setGame(wxapkg)
 		case enum.GAME_PLUGIN:
+			wxapkg.Option = &config.WxapkgOption{
+				ServiceSource: filepath.Join(wxapkg.SourcePath, enum.Plugin),
+				SetAppConfig:  false,
+			}
+			d.setGame(wxapkg)
 		}
 	}
 }
 
-func setApp(wxapkg *config.WxapkgInfo) {
+func (d *WxapkgDecompiler) setApp(wxapkg *config.WxapkgInfo) {
 	// 如果未解压，则不进行解析
 	if !wxapkg.IsExtracted {
 		return
@@ -140,7 +153,9 @@ func setApp(wxapkg *config.WxapkgInfo) {
 		wxapkg.Option.ViewSource = filepath.Join(wxapkg.SourcePath, enum.AppWxss)
 	}
 
-	wccVersion := util.GetWccVersion(wxapkg.Option.ViewSource)
+	output := d.Session.OutputSink()
+	viewContent, _ := output.ReadFile(wxapkg.Option.ViewSource)
+	wccVersion := util.GetWccVersionFromContent(string(viewContent))
 	if wccVersion != "" {
 		log.Printf(fmt.Sprintf("The package %s wcc version is: [%s]", wxapkg.SourcePath, wccVersion))
 	}
@@ -149,24 +164,74 @@ func setApp(wxapkg *config.WxapkgInfo) {
 		if wxapkg.Option.AppConfigSource == "" {
 			wxapkg.Option.AppConfigSource = filepath.Join(wxapkg.SourcePath, enum.App_Config)
 		}
-		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.ConfigParser{})
+		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.ConfigParser{Output: output})
 	}
 
-	wxapkg.Parsers = append(wxapkg.Parsers, &unpack.JavaScriptParser{OutputDir: OutputDir})
-	wxapkg.Parsers = append(wxapkg.Parsers, &unpack.XssParser{OutputDir: OutputDir})
+	wxapkg.Parsers = append(wxapkg.Parsers, &unpack.JavaScriptParser{OutputDir: d.OutputDir, Output: output})
+	wxapkg.Parsers = append(wxapkg.Parsers, &unpack.XssParser{OutputDir: d.OutputDir, Deletion: d.Session.Deletion, Output: output})
+
+	// 插件的 WXML 保存到插件目录
+	xmlDir := d.OutputDir
+	if isAppPlugin(wxapkg) {
+		xmlDir = wxapkg.SourcePath
+	}
+	indent, width := d.Session.Config.GetInt("indent"), d.Session.Config.GetInt("width")
 	if isParserV1(wxapkg) {
-		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.XmlParser{OutputDir: OutputDir, Version: "v1"})
+		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.XmlParser{OutputDir: xmlDir, Version: "v1", Output: output, Indent: indent, Width: width})
 	} else if isParserV2(wxapkg) {
-		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.XmlParser{OutputDir: OutputDir, Version: "v2"})
+		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.XmlParser{OutputDir: xmlDir, Version: "v2", Output: output, Indent: indent, Width: width})
+	}
+
+	if isAppPlugin(wxapkg) {
+		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.PluginParser{OutputDir: d.OutputDir, Output: output})
+	}
+
+	// 清除无用文件
+	d.cleanApp(wxapkg.SourcePath)
+}
+
+func (d *WxapkgDecompiler) setGame(wxapkg *config.WxapkgInfo) {
+	// 如果未解压，则不进行解析
+	if !wxapkg.IsExtracted {
+		return
+	}
+
+	// 未找到分包所属的主包时无法确定分包目录
+	if wxapkg.SourcePath == "" {
+		log.Printf("未找到 %s 包所属的主包，跳过还原\n", wxapkg.WxapkgType)
+		return
 	}
 
+	output := d.Session.OutputSink()
+	if wxapkg.Option.SetAppConfig {
+		wxapkg.Parsers = append(wxapkg.Parsers, &unpack.GameConfigParser{OutputDir: d.OutputDir, Output: output})
+	}
+
+	// 拆分 game.js 或 plugin.js 中的 define 模块
+	wxapkg.Parsers = append(wxapkg.Parsers, &unpack.JavaScriptParser{OutputDir: d.OutputDir, Output: output})
+
 	// 清除无用文件
-	cleanApp(wxapkg.SourcePath)
+	d.cleanGame(wxapkg.SourcePath)
+}
+
+func (d *WxapkgDecompiler) cleanGame(path string) {
+	// 文件删除管理器
+	manager := d.Session.Deletion
+
+	// game.js 及 plugin.js 会被拆分出的同名模块覆盖，不删除
+	unlinks := []string{
+		"app-config.json",
+		"subContext.js",
+	}
+
+	for _, unlink := range unlinks {
+		manager.AddFile(filepath.Join(path, unlink))
+	}
 }
 
-func cleanApp(path string) {
-	// 创建文件删除管理器
-	manager := config.NewFileDeletionManager()
+func (d *WxapkgDecompiler) cleanApp(path string) {
+	// 文件删除管理器
+	manager := d.Session.Deletion
 
 	// 删除相关的JS文件, unlinks
 	unlinks := []string{
@@ -0,0 +1,174 @@
+package restore
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/enum"
+	"github.com/Ackites/KillWxapkg/internal/sink"
+)
+
+// 开发者工具项目配置说明
+const projectConfigDescription = "项目配置文件，详见文档：https://developers.weixin.qq.com/miniprogram/dev/devtools/projectconfig.html"
+
+// 未知 AppID 时使用开发者工具的测试号
+const touristAppID = "touristappid"
+
+// ProjectConfig 开发者工具的 project.config.json
+type ProjectConfig struct {
+	Description     string         `json:"description"`
+	AppID           string         `json:"appid"`
+	ProjectName     string         `json:"projectname"`
+	CompileType     string         `json:"compileType"`
+	LibVersion      string         `json:"libVersion,omitempty"`
+	MiniprogramRoot string         `json:"miniprogramRoot,omitempty"`
+	PluginRoot      string         `json:"pluginRoot,omitempty"`
+	Setting         ProjectSetting `json:"setting"`
+}
+
+// ProjectSetting 项目的编译设置，还原出的代码已经过编译，不再转换或压缩
+type ProjectSetting struct {
+	URLCheck     bool `json:"urlCheck"`
+	ES6          bool `json:"es6"`
+	Enhance      bool `json:"enhance"`
+	PostCSS      bool `json:"postcss"`
+	Minified     bool `json:"minified"`
+	CheckSiteMap bool `json:"checkSiteMap"`
+}
+
+// ProjectPrivateConfig 开发者工具的 project.private.config.json，仅覆盖本地设置
+type ProjectPrivateConfig struct {
+	Description string                 `json:"description"`
+	ProjectName string                 `json:"projectname"`
+	Setting     map[string]interface{} `json:"setting"`
+}
+
+// libVersionRegexp 匹配 __wxConfig 中的基础库版本
+var libVersionRegexp = regexp.MustCompile(`["']?libVersion["']?\s*[:=]\s*["']([0-9][0-9.]*)["']`)
+
+// saveProjectConfig 根据主包或插件生成开发者工具的项目配置，privateConfig 为 true 时额外生成关闭域名校验的本地配置
+func saveProjectConfig(session *config.Session, outputDir string, privateConfig bool) {
+	output := session.OutputSink()
+
+	// 工程类型由主包或插件决定，按包名顺序选择，多个主包时结果保持一致
+	names := make([]string, 0, len(session.Packages.Packages))
+	for name := range session.Packages.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var project *config.WxapkgInfo
+	for _, name := range names {
+		wxapkg := session.Packages.Packages[name]
+		if IsMainPackage(wxapkg) || isAppPlugin(wxapkg) {
+			if project == nil || isAppPlugin(project) {
+				project = wxapkg
+			}
+		}
+	}
+	if project == nil {
+		return
+	}
+
+	// 未知 AppID 时使用配置中的账号信息，仍未找到时使用测试号
+	appID := project.WxAppId
+	if appID == "" {
+		appID = accountAppID(output, project)
+	}
+	projectName := appID
+	if projectName == "" {
+		projectName = filepath.Base(outputDir)
+	}
+	if appID == "" {
+		appID = touristAppID
+	}
+
+	projectConfig := ProjectConfig{
+		Description: projectConfigDescription,
+		AppID:       appID,
+		ProjectName: projectName,
+		CompileType: "miniprogram",
+		LibVersion:  findLibVersion(output, project),
+		Setting: ProjectSetting{
+			URLCheck: true,
+		},
+	}
+	switch {
+	case project.WxapkgType == enum.GAME:
+		projectConfig.CompileType = "game"
+	case isAppPlugin(project):
+		projectConfig.CompileType = "plugin"
+		projectConfig.MiniprogramRoot = enum.MiniprogramRoot + "/"
+		projectConfig.PluginRoot = enum.PluginRoot + "/"
+	default:
+		projectConfig.MiniprogramRoot = "./"
+	}
+
+	content, _ := json.MarshalIndent(projectConfig, "", "    ")
+	if err := saveProjectFile(output, filepath.Join(outputDir, "project.config.json"), content); err != nil {
+		log.Printf("保存 project.config.json 失败: %v\n", err)
+	}
+
+	if !privateConfig {
+		return
+	}
+	privateContent, _ := json.MarshalIndent(ProjectPrivateConfig{
+		Description: projectConfigDescription,
+		ProjectName: projectName,
+		Setting:     map[string]interface{}{"urlCheck": false},
+	}, "", "    ")
+	if err := saveProjectFile(output, filepath.Join(outputDir, "project.private.config.json"), privateContent); err != nil {
+		log.Printf("保存 project.private.config.json 失败: %v\n", err)
+	}
+}
+
+// accountAppID 读取包配置 accountInfo 中的 AppID，未找到时返回空
+func accountAppID(output sink.Sink, wxapkg *config.WxapkgInfo) string {
+	content, err := output.ReadFile(filepath.Join(wxapkg.SourcePath, enum.App_Config))
+	if err != nil {
+		return ""
+	}
+	var appConfig struct {
+		AccountInfo struct {
+			AppID string `json:"appId"`
+		} `json:"accountInfo"`
+	}
+	if err := json.Unmarshal(content, &appConfig); err != nil {
+		return ""
+	}
+	return appConfig.AccountInfo.AppID
+}
+
+// findLibVersion 从包的配置及框架代码中查找 __wxConfig 声明的基础库版本，未找到时返回空
+func findLibVersion(output sink.Sink, wxapkg *config.WxapkgInfo) string {
+	sources := []string{filepath.Join(wxapkg.SourcePath, enum.App_Config)}
+	if wxapkg.Option != nil {
+		sources = append(sources, wxapkg.Option.ViewSource, wxapkg.Option.ServiceSource)
+	}
+	for _, source := range sources {
+		if source == "" {
+			continue
+		}
+		content, err := output.ReadFile(source)
+		if err != nil {
+			continue
+		}
+		if match := libVersionRegexp.FindSubmatch(content); match != nil {
+			return string(match[1])
+		}
+	}
+	return ""
+}
+
+// saveProjectFile 保存项目配置文件
+func saveProjectFile(output sink.Sink, filename string, content []byte) error {
+	if err := output.WriteFile(filename, content); err != nil {
+		return err
+	}
+	log.Printf("项目配置已保存到: %s\n", filename)
+	return nil
+}
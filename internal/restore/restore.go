@@ -2,25 +2,27 @@ package restore
 
 import (
 	"encoding/json"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/Ackites/KillWxapkg/internal/enum"
 
 	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
 	"github.com/Ackites/KillWxapkg/internal/unpack"
 )
 
 // fixSubpackageDir 修正子包目录
-func fixSubpackageDir(wxapkg *config.WxapkgInfo, outputDir string) string {
+func fixSubpackageDir(output sink.Sink, wxapkg *config.WxapkgInfo, outputDir string) string {
+	// 小游戏的分包配置为 subpackages
 	var e struct {
-		SubPackages []unpack.SubPackage `json:"subPackages"`
+		SubPackages     []unpack.SubPackage `json:"subPackages"`
+		GameSubPackages []unpack.SubPackage `json:"subpackages"`
 	}
-	content, _ := os.ReadFile(filepath.Join(outputDir, enum.App_Config))
+	content, _ := output.ReadFile(filepath.Join(outputDir, enum.App_Config))
 	_ = json.Unmarshal(content, &e)
 
-	for _, subPackage := range e.SubPackages {
+	for _, subPackage := range append(e.SubPackages, e.GameSubPackages...) {
 		root := subPackage.Root
 		if !strings.HasPrefix(root, "/") {
 			root = "/" + root
@@ -34,41 +36,47 @@ func fixSubpackageDir(wxapkg *config.WxapkgInfo, outputDir string) string {
 }
 
 // ProjectStructure 是否还原工程目录结构
-func ProjectStructure(outputDir string, restoreDir bool) {
+// session 为解包时使用的会话，包信息、配置和待删除文件均从中读取
+func ProjectStructure(session *config.Session, outputDir string, restoreDir bool) {
 	if !restoreDir {
 		return
 	}
 
-	configManager := config.NewSharedConfigManager()
+	// 文件删除管理器
+	manager := session.Deletion
 
-	// 创建文件删除管理器
-	manager := config.NewFileDeletionManager()
+	// 输出目标
+	output := session.OutputSink()
 
 	defer func() {
-		if noClean, ok := configManager.Get("noClean"); ok {
+		if noClean, ok := session.Config.Get("noClean"); ok {
 			if !noClean.(bool) {
 				// 执行删除文件操作
-				manager.DeleteFiles()
+				manager.DeleteFilesIn(output)
 			}
 		}
 	}()
 
 	// 包管理器
-	wxakpgManager := config.GetWxapkgManager()
+	wxakpgManager := session.Packages
 
 	// 修正子包目录
 	for _, wxapkg := range wxakpgManager.Packages {
 		if IsSubpackage(wxapkg) {
-			wxapkg.SourcePath = fixSubpackageDir(wxapkg, outputDir)
+			wxapkg.SourcePath = fixSubpackageDir(output, wxapkg, outputDir)
 		}
 	}
 
 	// 反编译
-	decompiler := new(WxapkgDecompiler)
+	decompiler := &WxapkgDecompiler{OutputDir: outputDir, Session: session}
 	// 执行反编译操作
-	decompiler.Decompile(outputDir)
+	decompiler.Decompile()
 
 	// 创建命令执行器, 执行解析器
 	executor := NewCommandExecutor(wxakpgManager)
 	executor.ExecuteAll()
+
+	// 生成开发者工具的项目配置
+	privateConfig, _ := session.Config.Get("privateConfig")
+	saveProjectConfig(session, outputDir, privateConfig == true)
 }
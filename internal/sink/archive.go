@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Archive 输出为单个归档，文件先保存在内存中，关闭时按路径顺序写入归档
+type Archive struct {
+	*Memory
+	write func(w io.Writer, m *Memory) error
+}
+
+// NewZip 创建输出为 zip 归档的输出，path 为归档路径，同时作为虚拟的输出根目录
+func NewZip(path string) *Archive {
+	return &Archive{Memory: NewMemory(path), write: writeZip}
+}
+
+// NewTarGz 创建输出为 tar.gz 归档的输出，path 为归档路径，同时作为虚拟的输出根目录
+func NewTarGz(path string) *Archive {
+	return &Archive{Memory: NewMemory(path), write: writeTarGz}
+}
+
+// Close 将所有文件写入归档，调用前所有写入须已完成
+func (a *Archive) Close() error {
+	path := a.Root()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Printf("关闭文件 %s 失败: %v\n", f.Name(), err)
+		}
+	}(f)
+
+	return a.write(f, a.Memory)
+}
+
+// writeZip 以 zip 格式写入所有文件
+func writeZip(w io.Writer, m *Memory) error {
+	zw := zip.NewWriter(w)
+	modified := time.Now()
+	for _, name := range m.Names() {
+		content := m.files[name]
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz 以 tar.gz 格式写入所有文件
+func writeTarGz(w io.Writer, m *Memory) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	modified := time.Now()
+	for _, name := range m.Names() {
+		content := m.files[name]
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  modified,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
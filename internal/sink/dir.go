@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Dir 写入磁盘目录的输出
+type Dir struct {
+	root string
+}
+
+// NewDir 创建写入磁盘目录的输出，root 为空时路径按原样使用
+func NewDir(root string) *Dir {
+	return &Dir{root: root}
+}
+
+func (d *Dir) Root() string {
+	return d.root
+}
+
+func (d *Dir) WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0755)
+}
+
+func (d *Dir) Create(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+}
+
+func (d *Dir) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (d *Dir) Exists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+func (d *Dir) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Walk 遍历目录下的文件，目录不存在时不做任何操作
+func (d *Dir) Walk(dir string, fn func(name string) error) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+func (d *Dir) Close() error {
+	return nil
+}
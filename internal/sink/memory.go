@@ -0,0 +1,159 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory 保存在内存中的输出，不写入磁盘，可供调用方直接读取结果
+type Memory struct {
+	root  string
+	mu    sync.RWMutex
+	files map[string][]byte // 相对于根目录的 / 分隔路径 -> 文件内容
+}
+
+// NewMemory 创建内存输出，root 为虚拟的输出根目录
+func NewMemory(root string) *Memory {
+	return &Memory{root: root, files: make(map[string][]byte)}
+}
+
+func (m *Memory) Root() string {
+	return m.root
+}
+
+func (m *Memory) WriteFile(name string, data []byte) error {
+	key, err := relative(m.root, name)
+	if err != nil {
+		return err
+	}
+	if key == "." {
+		return fmt.Errorf("无法写入输出根目录 %s", m.root)
+	}
+	content := make([]byte, len(data))
+	copy(content, data)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[key] = content
+	return nil
+}
+
+func (m *Memory) Create(name string) (io.WriteCloser, error) {
+	if _, err := relative(m.root, name); err != nil {
+		return nil, err
+	}
+	return &memoryFile{memory: m, name: name}, nil
+}
+
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	key, err := relative(m.root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	content, ok := m.files[key]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return content, nil
+}
+
+func (m *Memory) Exists(name string) bool {
+	key, err := relative(m.root, name)
+	if err != nil {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.files[key]
+	return ok
+}
+
+func (m *Memory) Remove(name string) error {
+	key, err := relative(m.root, name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[key]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, key)
+	return nil
+}
+
+// Walk 按路径顺序遍历目录下的文件，遍历的是调用时的快照，fn 中可以写入或删除文件
+func (m *Memory) Walk(dir string, fn func(name string) error) error {
+	prefix, err := relative(m.root, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range m.Names() {
+		if prefix != "." && !strings.HasPrefix(key, prefix+"/") {
+			continue
+		}
+		if err := fn(filepath.Join(m.root, filepath.FromSlash(key))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names 返回所有文件相对于根目录的 / 分隔路径，按路径排序
+func (m *Memory) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.files))
+	for key := range m.files {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MoveInto 将所有文件移动到 dir 目录下，保持相对路径不变，dir 须在根目录内
+func (m *Memory) MoveInto(dir string) error {
+	prefix, err := relative(m.root, dir)
+	if err != nil {
+		return err
+	}
+	if prefix == "." {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make(map[string][]byte, len(m.files))
+	for key, content := range m.files {
+		files[prefix+"/"+key] = content
+	}
+	m.files = files
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// memoryFile 流式写入内存输出的文件，关闭时保存
+type memoryFile struct {
+	bytes.Buffer
+	memory *Memory
+	name   string
+}
+
+func (f *memoryFile) Close() error {
+	return f.memory.WriteFile(f.name, f.Bytes())
+}
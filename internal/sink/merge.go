@@ -0,0 +1,191 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ConflictPolicy 多个包写入同一路径且内容不同时的处理方式
+type ConflictPolicy string
+
+const (
+	ConflictOverwrite ConflictPolicy = "overwrite"  // 后合并的包覆盖已有文件
+	ConflictKeepFirst ConflictPolicy = "keep-first" // 保留先合并的包中的文件
+	ConflictKeepBoth  ConflictPolicy = "keep-both"  // 保留已有文件，后合并的文件加后缀另存
+	ConflictFail      ConflictPolicy = "fail"       // 不合并发生冲突的包并返回错误
+)
+
+// ErrConflict 冲突处理方式为 fail 时，包与已合并的文件冲突
+var ErrConflict = errors.New("文件冲突")
+
+// ParseConflictPolicy 解析冲突处理方式，为空时使用 overwrite
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(s); policy {
+	case "":
+		return ConflictOverwrite, nil
+	case ConflictOverwrite, ConflictKeepFirst, ConflictKeepBoth, ConflictFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("未知的冲突处理方式: %s，可选 overwrite、keep-first、keep-both、fail", s)
+}
+
+// Conflict 两个包写入同一路径且内容不同
+type Conflict struct {
+	Path     string         `json:"path"`
+	Existing string         `json:"existing"`          // 已有文件来自的包，合并前已存在时为空
+	Incoming string         `json:"incoming"`          // 后合并的包
+	Policy   ConflictPolicy `json:"policy"`            // 采用的处理方式
+	SavedAs  string         `json:"savedAs,omitempty"` // keep-both 时后合并文件的保存路径
+}
+
+// String 描述冲突及其处理结果，用于日志输出
+func (c Conflict) String() string {
+	existing := c.Existing
+	if existing == "" {
+		existing = "已存在的文件"
+	}
+	var result string
+	switch c.Policy {
+	case ConflictOverwrite:
+		result = "已被 " + c.Incoming + " 覆盖"
+	case ConflictKeepFirst:
+		result = "保留 " + existing + " 中的文件"
+	case ConflictKeepBoth:
+		result = c.Incoming + " 中的文件另存为 " + c.SavedAs
+	case ConflictFail:
+		result = c.Incoming + " 未合并"
+	}
+	return fmt.Sprintf("%s: %s 与 %s 内容不同, %s", c.Path, existing, c.Incoming, result)
+}
+
+// Merger 将多个包的解包结果依次合并到同一个输出目标，记录每个文件来自的包及发生的冲突
+type Merger struct {
+	Output Sink
+	Policy ConflictPolicy
+
+	mu        sync.Mutex
+	owners    map[string]string
+	conflicts []Conflict
+}
+
+// NewMerger 创建合并到 output 的 Merger
+func NewMerger(output Sink, policy ConflictPolicy) *Merger {
+	return &Merger{Output: output, Policy: policy, owners: make(map[string]string)}
+}
+
+// Merge 将 src 中的文件合并到输出目标，origin 为文件来自的包
+// 内容相同的文件不视为冲突，处理方式为 fail 时只要有一个冲突，整个包都不会合并
+func (m *Merger) Merge(src Sink, origin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names, conflicting []string
+	identical := make(map[string]bool)
+	differs := make(map[string]bool)
+	err := src.Walk(src.Root(), func(name string) error {
+		names = append(names, name)
+		if !m.Output.Exists(name) {
+			return nil
+		}
+		incoming, err := src.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		existing, err := m.Output.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(existing, incoming) {
+			identical[name] = true
+		} else {
+			differs[name] = true
+			conflicting = append(conflicting, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if m.Policy == ConflictFail && len(conflicting) > 0 {
+		for _, name := range conflicting {
+			m.record(name, origin, "")
+		}
+		return fmt.Errorf("%w: %s 等 %d 个文件与已合并的包冲突", ErrConflict, m.display(conflicting[0]), len(conflicting))
+	}
+
+	for _, name := range names {
+		if identical[name] {
+			continue
+		}
+		target := name
+		if differs[name] {
+			switch m.Policy {
+			case ConflictKeepFirst:
+				m.record(name, origin, "")
+				continue
+			case ConflictKeepBoth:
+				target = m.freeName(name)
+				m.record(name, origin, target)
+			default:
+				m.record(name, origin, "")
+			}
+		}
+
+		data, err := src.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := m.Output.WriteFile(target, data); err != nil {
+			return err
+		}
+		m.owners[target] = origin
+	}
+	return nil
+}
+
+// record 记录冲突
+func (m *Merger) record(name, origin, savedAs string) {
+	m.conflicts = append(m.conflicts, Conflict{
+		Path:     m.display(name),
+		Existing: m.owners[name],
+		Incoming: origin,
+		Policy:   m.Policy,
+		SavedAs:  m.display(savedAs),
+	})
+}
+
+// display 将路径转换为相对于输出根目录的形式
+func (m *Merger) display(name string) string {
+	if name == "" {
+		return ""
+	}
+	if rel, err := relative(m.Output.Root(), name); err == nil && m.Output.Root() != "" {
+		return rel
+	}
+	return filepath.ToSlash(name)
+}
+
+// freeName 为 keep-both 生成不存在的文件名，如 app.js -> app.conflict1.js
+func (m *Merger) freeName(name string) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := stem + ".conflict" + strconv.Itoa(i) + ext
+		if !m.Output.Exists(candidate) {
+			return candidate
+		}
+	}
+}
+
+// Conflicts 返回已发生的冲突，按合并顺序排列
+func (m *Merger) Conflicts() []Conflict {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Conflict(nil), m.conflicts...)
+}
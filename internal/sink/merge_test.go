@@ -0,0 +1,211 @@
+package sink
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// memoryWith 创建包含指定文件的内存输出，文件名为相对于根目录的 / 分隔路径
+func memoryWith(t *testing.T, root string, files map[string]string) *Memory {
+	t.Helper()
+	m := NewMemory(root)
+	for name, content := range files {
+		if err := m.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// memoryFiles 返回内存输出中的所有文件
+func memoryFiles(t *testing.T, m *Memory) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	for _, name := range m.Names() {
+		data, err := m.ReadFile(filepath.Join(m.Root(), filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	return files
+}
+
+func TestMerge(t *testing.T) {
+	const root = "out"
+	first := map[string]string{
+		"app.js":    "first",
+		"common.js": "same",
+		"LICENSE":   "MIT",
+		"first.js":  "1",
+	}
+	second := map[string]string{
+		"app.js":         "second",
+		"common.js":      "same",
+		"LICENSE":        "GPL",
+		"second.js":      "2",
+		"pages/index.js": "Page({})",
+	}
+
+	tests := []struct {
+		policy    ConflictPolicy
+		wantErr   bool
+		files     map[string]string
+		conflicts []Conflict
+	}{
+		{
+			policy: ConflictOverwrite,
+			files: map[string]string{
+				"app.js": "second", "common.js": "same", "LICENSE": "GPL",
+				"first.js": "1", "second.js": "2", "pages/index.js": "Page({})",
+			},
+			conflicts: []Conflict{
+				{Path: "LICENSE", Existing: "first", Incoming: "second", Policy: ConflictOverwrite},
+				{Path: "app.js", Existing: "first", Incoming: "second", Policy: ConflictOverwrite},
+			},
+		},
+		{
+			policy: ConflictKeepFirst,
+			files: map[string]string{
+				"app.js": "first", "common.js": "same", "LICENSE": "MIT",
+				"first.js": "1", "second.js": "2", "pages/index.js": "Page({})",
+			},
+			conflicts: []Conflict{
+				{Path: "LICENSE", Existing: "first", Incoming: "second", Policy: ConflictKeepFirst},
+				{Path: "app.js", Existing: "first", Incoming: "second", Policy: ConflictKeepFirst},
+			},
+		},
+		{
+			policy: ConflictKeepBoth,
+			files: map[string]string{
+				"app.js": "first", "app.conflict1.js": "second",
+				"LICENSE": "MIT", "LICENSE.conflict1": "GPL",
+				"common.js": "same", "first.js": "1", "second.js": "2", "pages/index.js": "Page({})",
+			},
+			conflicts: []Conflict{
+				{Path: "LICENSE", Existing: "first", Incoming: "second", Policy: ConflictKeepBoth, SavedAs: "LICENSE.conflict1"},
+				{Path: "app.js", Existing: "first", Incoming: "second", Policy: ConflictKeepBoth, SavedAs: "app.conflict1.js"},
+			},
+		},
+		{
+			policy:  ConflictFail,
+			wantErr: true,
+			files:   first,
+			conflicts: []Conflict{
+				{Path: "LICENSE", Existing: "first", Incoming: "second", Policy: ConflictFail},
+				{Path: "app.js", Existing: "first", Incoming: "second", Policy: ConflictFail},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			output := NewMemory(root)
+			merger := NewMerger(output, tt.policy)
+			if err := merger.Merge(memoryWith(t, root, first), "first"); err != nil {
+				t.Fatal(err)
+			}
+			err := merger.Merge(memoryWith(t, root, second), "second")
+			if tt.wantErr {
+				if !errors.Is(err, ErrConflict) {
+					t.Fatalf("Merge() error = %v, want ErrConflict", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := memoryFiles(t, output); !reflect.DeepEqual(got, tt.files) {
+				t.Errorf("output = %q, want %q", got, tt.files)
+			}
+			if got := merger.Conflicts(); !reflect.DeepEqual(got, tt.conflicts) {
+				t.Errorf("Conflicts() = %+v, want %+v", got, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeKeepBothNumbering(t *testing.T) {
+	const root = "out"
+	output := NewMemory(root)
+	merger := NewMerger(output, ConflictKeepBoth)
+	for _, origin := range []string{"a", "b", "c"} {
+		src := memoryWith(t, root, map[string]string{"pages/index.wxml": origin, "dir.v2/README": origin})
+		if err := merger.Merge(src, origin); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		"pages/index.wxml":           "a",
+		"pages/index.conflict1.wxml": "b",
+		"pages/index.conflict2.wxml": "c",
+		"dir.v2/README":              "a",
+		"dir.v2/README.conflict1":    "b",
+		"dir.v2/README.conflict2":    "c",
+	}
+	if got := memoryFiles(t, output); !reflect.DeepEqual(got, want) {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	conflicts := merger.Conflicts()
+	if len(conflicts) != 4 {
+		t.Fatalf("Conflicts() = %+v, want 4", conflicts)
+	}
+	if c := conflicts[3]; c.Path != "pages/index.wxml" || c.Existing != "a" || c.Incoming != "c" || c.SavedAs != "pages/index.conflict2.wxml" {
+		t.Errorf("last conflict = %+v", c)
+	}
+}
+
+func TestMergeFailAllOrNothing(t *testing.T) {
+	const root = "out"
+	output := memoryWith(t, root, map[string]string{"app.js": "existing"})
+	merger := NewMerger(output, ConflictFail)
+
+	// 只有一个文件冲突时，其他文件同样不合并
+	conflicting := memoryWith(t, root, map[string]string{"a.js": "a", "app.js": "new", "z.js": "z"})
+	if err := merger.Merge(conflicting, "pkg"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Merge() error = %v, want ErrConflict", err)
+	}
+	if got, want := memoryFiles(t, output), map[string]string{"app.js": "existing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("output after failed merge = %q, want %q", got, want)
+	}
+	if got, want := merger.Conflicts(), []Conflict{{Path: "app.js", Incoming: "pkg", Policy: ConflictFail}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Conflicts() = %+v, want %+v", got, want)
+	}
+
+	// 内容相同的文件不是冲突，之后的包仍可合并
+	clean := memoryWith(t, root, map[string]string{"app.js": "existing", "b.js": "b"})
+	if err := merger.Merge(clean, "clean"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := memoryFiles(t, output), map[string]string{"app.js": "existing", "b.js": "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if len(merger.Conflicts()) != 1 {
+		t.Errorf("Conflicts() = %+v, want 1", merger.Conflicts())
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ConflictPolicy
+		wantErr bool
+	}{
+		{"", ConflictOverwrite, false},
+		{"overwrite", ConflictOverwrite, false},
+		{"keep-first", ConflictKeepFirst, false},
+		{"keep-both", ConflictKeepBoth, false},
+		{"fail", ConflictFail, false},
+		{"Fail", "", true},
+		{"skip", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseConflictPolicy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseConflictPolicy(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
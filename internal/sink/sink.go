@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Sink 输出目标，解包和还原的所有文件都通过它写入和读回
+// 路径与直接写入磁盘时相同，均为输出根目录下的完整路径，由实现转换为自身的存储位置
+type Sink interface {
+	// Root 输出根目录
+	Root() string
+	// WriteFile 写入文件，已存在时覆盖
+	WriteFile(name string, data []byte) error
+	// Create 创建文件用于流式写入，关闭后写入完成
+	Create(name string) (io.WriteCloser, error)
+	// ReadFile 读取已写入的文件
+	ReadFile(name string) ([]byte, error)
+	// Exists 文件是否存在
+	Exists(name string) bool
+	// Remove 删除文件
+	Remove(name string) error
+	// Walk 按路径顺序遍历目录下的所有文件
+	Walk(dir string, fn func(name string) error) error
+	// Close 完成输出，归档类的输出在此时写入磁盘
+	Close() error
+}
+
+// ForPath 根据输出路径选择输出目标
+// 以 .zip、.tar.gz、.tgz 结尾时输出为单个归档，否则写入目录
+func ForPath(output string) Sink {
+	lower := strings.ToLower(output)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return NewZip(output)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return NewTarGz(output)
+	default:
+		return NewDir(output)
+	}
+}
+
+// Copy 将 src 中的所有文件写入 dst，路径保持不变
+func Copy(dst, src Sink) error {
+	return src.Walk(src.Root(), func(name string) error {
+		data, err := src.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return dst.WriteFile(name, data)
+	})
+}
+
+// relative 将完整路径转换为相对于根目录的 / 分隔路径，路径不在根目录内时返回错误
+func relative(root, name string) (string, error) {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(name))
+	if err != nil {
+		return "", fmt.Errorf("路径 %s 不在输出目录 %s 内: %w", name, root, err)
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("路径 %s 不在输出目录 %s 内", name, root)
+	}
+	return rel, nil
+}
@@ -0,0 +1,389 @@
+package sink
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var testFiles = map[string]string{
+	"app.js":                  "App({})",
+	"pages/index/index.wxml":  "<view></view>",
+	"pages/index/index.js":    "Page({})",
+	"static/img/logo.png":     "\x89PNG\x00",
+	"pages/index/empty.json":  "",
+	"subpackage/pages/a.wxss": ".a{}",
+}
+
+func TestRelative(t *testing.T) {
+	root := filepath.Join("out", "app")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: filepath.Join(root, "a.js"), want: "a.js"},
+		{name: filepath.Join(root, "pages", "index", "index.js"), want: "pages/index/index.js"},
+		{name: root, want: "."},
+		{name: root + string(filepath.Separator), want: "."},
+		{name: filepath.Join(root, "a", "..", "b.js"), want: "b.js"},
+		{name: filepath.Join(root, "..", "x.js"), wantErr: true},
+		{name: filepath.Join("out", "app2", "a.js"), wantErr: true},
+		{name: "out", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := relative(root, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("relative(%q, %q) error = %v, wantErr %v", root, tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("relative(%q, %q) = %q, want %q", root, tt.name, got, tt.want)
+		}
+	}
+}
+
+// writeTestFiles 写入测试文件，偶数个文件通过 Create 流式写入
+func writeTestFiles(t *testing.T, s Sink) {
+	t.Helper()
+	i := 0
+	for _, name := range sortedNames(testFiles) {
+		path := filepath.Join(s.Root(), filepath.FromSlash(name))
+		if i%2 == 0 {
+			if err := s.WriteFile(path, []byte(testFiles[name])); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			w, err := s.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(w, testFiles[name]); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		i++
+	}
+}
+
+// walkNames 返回目录下所有文件相对于根目录的 / 分隔路径
+func walkNames(t *testing.T, s Sink, dir string) []string {
+	t.Helper()
+	var names []string
+	err := s.Walk(dir, func(name string) error {
+		rel, err := filepath.Rel(s.Root(), name)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestSinkRoundTrip(t *testing.T) {
+	sinks := map[string]func(t *testing.T) Sink{
+		"dir": func(t *testing.T) Sink {
+			return NewDir(t.TempDir())
+		},
+		"memory": func(t *testing.T) Sink {
+			return NewMemory(filepath.Join("virtual", "out"))
+		},
+		"temp": func(t *testing.T) Sink {
+			s, err := NewTempDir(filepath.Join("virtual", "out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+
+	for kind, newSink := range sinks {
+		t.Run(kind, func(t *testing.T) {
+			s := newSink(t)
+			defer func() { _ = s.Close() }()
+			writeTestFiles(t, s)
+			root := s.Root()
+
+			for name, content := range testFiles {
+				path := filepath.Join(root, filepath.FromSlash(name))
+				if !s.Exists(path) {
+					t.Errorf("Exists(%s) = false", name)
+				}
+				data, err := s.ReadFile(path)
+				if err != nil || string(data) != content {
+					t.Errorf("ReadFile(%s) = %q, %v, want %q", name, data, err, content)
+				}
+			}
+			if s.Exists(filepath.Join(root, "pages")) {
+				t.Errorf("Exists(pages) = true for a directory")
+			}
+
+			if got, want := walkNames(t, s, root), sortedNames(testFiles); !reflect.DeepEqual(got, want) {
+				t.Errorf("Walk(root) = %q, want %q", got, want)
+			}
+			if got, want := walkNames(t, s, filepath.Join(root, "pages", "index")), []string{"pages/index/empty.json", "pages/index/index.js", "pages/index/index.wxml"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Walk(pages/index) = %q, want %q", got, want)
+			}
+			if got := walkNames(t, s, filepath.Join(root, "missing")); len(got) != 0 {
+				t.Errorf("Walk(missing) = %q, want none", got)
+			}
+
+			// 覆盖写入
+			appJs := filepath.Join(root, "app.js")
+			if err := s.WriteFile(appJs, []byte("App({a:1})")); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := s.ReadFile(appJs); string(data) != "App({a:1})" {
+				t.Errorf("ReadFile(app.js) after overwrite = %q", data)
+			}
+
+			if err := s.Remove(appJs); err != nil {
+				t.Fatal(err)
+			}
+			if s.Exists(appJs) {
+				t.Errorf("Exists(app.js) after Remove = true")
+			}
+			if _, err := s.ReadFile(appJs); !os.IsNotExist(err) {
+				t.Errorf("ReadFile(app.js) after Remove error = %v, want not exist", err)
+			}
+			if err := s.Remove(appJs); err == nil {
+				t.Errorf("Remove(app.js) twice error = nil")
+			}
+		})
+	}
+}
+
+func TestSinkOutsideRoot(t *testing.T) {
+	root := filepath.Join("virtual", "out")
+	temp, err := NewTempDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = temp.Close() }()
+
+	for kind, s := range map[string]Sink{"memory": NewMemory(root), "temp": temp} {
+		outside := filepath.Join(root, "..", "escape.js")
+		if err := s.WriteFile(outside, nil); err == nil {
+			t.Errorf("%s: WriteFile outside root error = nil", kind)
+		}
+		if _, err := s.Create(outside); err == nil {
+			t.Errorf("%s: Create outside root error = nil", kind)
+		}
+		if err := s.WriteFile(root, nil); err == nil {
+			t.Errorf("%s: WriteFile(root) error = nil", kind)
+		}
+		if s.Exists(outside) {
+			t.Errorf("%s: Exists outside root = true", kind)
+		}
+		if err := s.Walk(outside, func(string) error { return nil }); err == nil {
+			t.Errorf("%s: Walk outside root error = nil", kind)
+		}
+	}
+}
+
+func TestMemoryWalkSnapshot(t *testing.T) {
+	m := NewMemory("out")
+	writeTestFiles(t, m)
+
+	// 遍历时删除和写入文件不影响本次遍历
+	var walked []string
+	err := m.Walk("out", func(name string) error {
+		walked = append(walked, name)
+		if err := m.Remove(name); err != nil {
+			return err
+		}
+		return m.WriteFile(name+".bak", nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(walked) != len(testFiles) {
+		t.Errorf("walked %d files, want %d", len(walked), len(testFiles))
+	}
+	for _, name := range m.Names() {
+		if filepath.Ext(name) != ".bak" {
+			t.Errorf("unexpected file %s", name)
+		}
+	}
+}
+
+func TestMoveInto(t *testing.T) {
+	root := filepath.Join("virtual", "out")
+	stagings := map[string]func(t *testing.T) Staging{
+		"memory": func(t *testing.T) Staging {
+			return NewMemory(root)
+		},
+		"temp": func(t *testing.T) Staging {
+			s, err := NewTempDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+
+	for kind, newStaging := range stagings {
+		t.Run(kind, func(t *testing.T) {
+			s := newStaging(t)
+			defer func() { _ = s.Close() }()
+			writeTestFiles(t, s)
+
+			if err := s.MoveInto(root); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := walkNames(t, s, root), sortedNames(testFiles); !reflect.DeepEqual(got, want) {
+				t.Errorf("after MoveInto(root) = %q, want %q", got, want)
+			}
+
+			if err := s.MoveInto(filepath.Join(root, "..", "escape")); err == nil {
+				t.Errorf("MoveInto outside root error = nil")
+			}
+
+			if err := s.MoveInto(filepath.Join(root, "wx1234", "main")); err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, name := range sortedNames(testFiles) {
+				want = append(want, "wx1234/main/"+name)
+			}
+			if got := walkNames(t, s, root); !reflect.DeepEqual(got, want) {
+				t.Errorf("after MoveInto = %q, want %q", got, want)
+			}
+			for name, content := range testFiles {
+				data, err := s.ReadFile(filepath.Join(root, "wx1234", "main", filepath.FromSlash(name)))
+				if err != nil || string(data) != content {
+					t.Errorf("ReadFile(%s) = %q, %v, want %q", name, data, err, content)
+				}
+				if s.Exists(filepath.Join(root, filepath.FromSlash(name))) {
+					t.Errorf("%s still exists at the old path", name)
+				}
+			}
+		})
+	}
+}
+
+func TestTempDirClose(t *testing.T) {
+	s, err := NewTempDir("out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, s)
+	if err := s.MoveInto(filepath.Join("out", "sub")); err != nil {
+		t.Fatal(err)
+	}
+	dir := s.dir
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("temporary directory %s still exists after Close: %v", dir, err)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(t *testing.T, path string) map[string]string
+	}{
+		{name: "out.zip", read: readZip},
+		{name: "out.tar.gz", read: readTarGz},
+		{name: "out.TGZ", read: readTarGz},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nested", tt.name)
+			s := ForPath(path)
+			if _, ok := s.(*Archive); !ok {
+				t.Fatalf("ForPath(%s) = %T, want *Archive", tt.name, s)
+			}
+			writeTestFiles(t, s)
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.read(t, path); !reflect.DeepEqual(got, testFiles) {
+				t.Errorf("archive content = %q, want %q", got, testFiles)
+			}
+		})
+	}
+
+	if _, ok := ForPath(filepath.Join("out", "app")).(*Dir); !ok {
+		t.Errorf("ForPath(out/app) is not a *Dir")
+	}
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func readTarGz(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+	return files
+}
+
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,151 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Staging 解包结果的暂存区，合并到输出目标后关闭以释放占用的空间
+type Staging interface {
+	Sink
+	// MoveInto 将所有文件移动到 dir 目录下，保持相对路径不变，dir 须在根目录内
+	MoveInto(dir string) error
+}
+
+// TempDir 暂存在磁盘临时目录中的输出，路径与输出根目录下相同，关闭时删除临时目录
+type TempDir struct {
+	root string
+	dir  string
+}
+
+// NewTempDir 创建暂存在磁盘临时目录中的输出，root 为虚拟的输出根目录
+func NewTempDir(root string) (*TempDir, error) {
+	dir, err := os.MkdirTemp("", "wxapkg-staging-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	return &TempDir{root: root, dir: dir}, nil
+}
+
+// path 将输出根目录下的路径转换为临时目录中的路径
+func (t *TempDir) path(name string) (string, error) {
+	key, err := relative(t.root, name)
+	if err != nil {
+		return "", err
+	}
+	if key == "." {
+		return "", fmt.Errorf("无法写入输出根目录 %s", t.root)
+	}
+	return filepath.Join(t.dir, filepath.FromSlash(key)), nil
+}
+
+func (t *TempDir) Root() string {
+	return t.root
+}
+
+func (t *TempDir) WriteFile(name string, data []byte) error {
+	path, err := t.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (t *TempDir) Create(name string) (io.WriteCloser, error) {
+	path, err := t.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func (t *TempDir) ReadFile(name string) ([]byte, error) {
+	path, err := t.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (t *TempDir) Exists(name string) bool {
+	path, err := t.path(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func (t *TempDir) Remove(name string) error {
+	path, err := t.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Walk 按路径顺序遍历目录下的文件，返回输出根目录下的路径
+func (t *TempDir) Walk(dir string, fn func(name string) error) error {
+	prefix, err := relative(t.root, dir)
+	if err != nil {
+		return err
+	}
+	start := filepath.Join(t.dir, filepath.FromSlash(prefix))
+	if _, err := os.Stat(start); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(start, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(t.dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.Join(t.root, rel))
+	})
+}
+
+// MoveInto 将所有文件移动到 dir 目录下，临时目录整体重命名，不复制文件内容
+func (t *TempDir) MoveInto(dir string) error {
+	prefix, err := relative(t.root, dir)
+	if err != nil {
+		return err
+	}
+	if prefix == "." {
+		return nil
+	}
+
+	parent, err := os.MkdirTemp("", "wxapkg-staging-")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	target := filepath.Join(parent, filepath.FromSlash(prefix))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		_ = os.RemoveAll(parent)
+		return err
+	}
+	if err := os.Rename(t.dir, target); err != nil {
+		_ = os.RemoveAll(parent)
+		return err
+	}
+	t.dir = parent
+	return nil
+}
+
+// Close 删除临时目录
+func (t *TempDir) Close() error {
+	return os.RemoveAll(t.dir)
+}
@@ -0,0 +1,383 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Stdin 表示从标准输入读取的输入参数
+const Stdin = "-"
+
+// File 可随机读取的包数据
+type File interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// Source 待处理的包，可以是磁盘上的文件、归档中的文件或标准输入
+type Source struct {
+	Name  string // 显示名称，归档中的文件为 归档路径!包内路径
+	Path  string // 用于推断 AppID 的路径，归档中的文件为 归档路径/包内路径
+	Entry string // 归档中的文件在最外层归档中的路径，嵌套的归档以 / 连接，用于命名输出文件，其他输入源为空
+	Size  int64
+	open  func() (File, error)
+}
+
+// Open 打开包数据，使用完毕后需要关闭
+func (s *Source) Open() (File, error) {
+	return s.open()
+}
+
+// FromFile 创建磁盘文件的输入源
+func FromFile(file string) (*Source, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{
+		Name: file,
+		Path: file,
+		Size: stat.Size(),
+		open: func() (File, error) {
+			return os.Open(file)
+		},
+	}, nil
+}
+
+// fromBytes 创建内存数据的输入源
+func fromBytes(name, path, entry string, data []byte) *Source {
+	return &Source{
+		Name:  name,
+		Path:  path,
+		Entry: entry,
+		Size:  int64(len(data)),
+		open: func() (File, error) {
+			return nopCloser{bytes.NewReader(data)}, nil
+		},
+	}
+}
+
+type nopCloser struct {
+	io.ReaderAt
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Collect 解析输入参数，返回所有待处理的包
+// 输入可以是目录、逗号分隔的文件列表或 -（标准输入），zip、tar、tar.gz 归档会递归读取其中的包，不会解压到磁盘
+func Collect(input, fileExt string) ([]*Source, error) {
+	var sources []*Source
+
+	if fileInfo, err := os.Stat(input); err == nil && fileInfo.IsDir() {
+		entries, err := os.ReadDir(input)
+		if err != nil {
+			return nil, fmt.Errorf("读取输入目录失败: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || (!strings.HasSuffix(entry.Name(), fileExt) && !IsArchiveName(entry.Name())) {
+				continue
+			}
+			found, err := collectFile(filepath.Join(input, entry.Name()), fileExt)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, found...)
+		}
+		return sources, nil
+	}
+
+	for _, item := range strings.Split(input, ",") {
+		if item == Stdin {
+			found, err := collectStdin(fileExt)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, found...)
+			continue
+		}
+
+		// 忽略不存在的文件
+		if _, err := os.Stat(item); err != nil {
+			continue
+		}
+		found, err := collectFile(item, fileExt)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, found...)
+	}
+	return sources, nil
+}
+
+// collectStdin 读取标准输入，内容为归档时读取其中的包
+func collectStdin(fileExt string) ([]*Source, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("读取标准输入失败: %v", err)
+	}
+	name := "stdin" + fileExt
+	if kind := detectArchive(data); kind != "" {
+		return expandArchive(kind, "stdin", "stdin", "", bytes.NewReader(data), int64(len(data)), fileExt)
+	}
+	return []*Source{fromBytes(Stdin, name, "", data)}, nil
+}
+
+// collectFile 处理磁盘上的文件，归档会被展开
+func collectFile(file, fileExt string) ([]*Source, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件信息失败: %v", err)
+	}
+
+	head := make([]byte, 512)
+	n, _ := f.ReadAt(head, 0)
+	kind := detectArchive(head[:n])
+	if kind == "" {
+		src, err := FromFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return []*Source{src}, nil
+	}
+
+	sources, err := expandArchive(kind, file, file, "", f, stat.Size(), fileExt)
+	if err != nil {
+		return nil, fmt.Errorf("读取归档 %s 失败: %w", file, err)
+	}
+
+	// 磁盘上的 zip 中未压缩的包直接从归档文件中读取
+	if kind == archiveZip {
+		for _, src := range sources {
+			if src.open == nil {
+				src.open = openStoredZipEntry(file, src)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// 归档类型
+const (
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+)
+
+// IsArchiveName 根据文件名判断是否为支持的归档
+func IsArchiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectArchive 根据文件头判断归档类型，不是归档时返回空字符串
+func detectArchive(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return archiveZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return archiveTarGz
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return archiveTar
+	}
+	return ""
+}
+
+// expandArchive 读取归档中的包，嵌套的归档会被递归展开
+// name 和 base 分别为归档的显示名称和路径，prefix 为归档在最外层归档中的路径前缀，归档中的包以其为前缀命名
+func expandArchive(kind, name, base, prefix string, r io.ReaderAt, size int64, fileExt string) ([]*Source, error) {
+	switch kind {
+	case archiveZip:
+		return expandZip(name, base, prefix, r, size, fileExt)
+	case archiveTar:
+		return expandTar(name, base, prefix, io.NewSectionReader(r, 0, size), fileExt)
+	case archiveTarGz:
+		gz, err := gzip.NewReader(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+		if err != nil {
+			return nil, err
+		}
+		defer func(gz *gzip.Reader) {
+			_ = gz.Close()
+		}(gz)
+		return expandTar(name, base, prefix, gz, fileExt)
+	}
+	return nil, fmt.Errorf("不支持的归档类型: %s", kind)
+}
+
+// expandZip 读取 zip 中的包
+// 未压缩的包不读入内存，open 留空，由调用方决定读取方式
+func expandZip(name, base, prefix string, r io.ReaderAt, size int64, fileExt string) ([]*Source, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []*Source
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entryName := name + "!" + file.Name
+		entryPath := path.Join(filepath.ToSlash(base), file.Name)
+		entry := prefix + file.Name
+
+		if strings.HasSuffix(file.Name, fileExt) && !IsArchiveName(file.Name) {
+			if file.Method == zip.Store && r != nil {
+				if _, isFile := r.(*os.File); isFile {
+					sources = append(sources, &Source{Name: entryName, Path: entryPath, Entry: entry, Size: int64(file.UncompressedSize64)})
+					continue
+				}
+			}
+			data, err := readZipEntry(file)
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 失败: %w", entryName, err)
+			}
+			sources = append(sources, fromBytes(entryName, entryPath, entry, data))
+			continue
+		}
+
+		if !IsArchiveName(file.Name) {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", entryName, err)
+		}
+		nested, err := expandNested(entryName, entryPath, entry+"/", data, fileExt)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, nested...)
+	}
+	return sources, nil
+}
+
+// readZipEntry 读取 zip 中的文件内容
+func readZipEntry(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(rc io.ReadCloser) {
+		_ = rc.Close()
+	}(rc)
+	return io.ReadAll(rc)
+}
+
+// openStoredZipEntry 打开磁盘上 zip 中未压缩的包，直接读取归档文件中的对应区域
+func openStoredZipEntry(archive string, src *Source) func() (File, error) {
+	entry := src.Entry
+	return func() (File, error) {
+		f, err := os.Open(archive)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		zr, err := zip.NewReader(f, stat.Size())
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		for _, file := range zr.File {
+			if file.Name != entry {
+				continue
+			}
+			offset, err := file.DataOffset()
+			if err != nil {
+				_ = f.Close()
+				return nil, err
+			}
+			return sectionFile{io.NewSectionReader(f, offset, int64(file.UncompressedSize64)), f}, nil
+		}
+		_ = f.Close()
+		return nil, fmt.Errorf("归档中未找到 %s", entry)
+	}
+}
+
+// sectionFile 归档文件中的一段区域，关闭时关闭归档文件
+type sectionFile struct {
+	*io.SectionReader
+	closer io.Closer
+}
+
+func (s sectionFile) Close() error {
+	return s.closer.Close()
+}
+
+// expandTar 顺序读取 tar 中的包，包内容读入内存
+func expandTar(name, base, prefix string, r io.Reader, fileExt string) ([]*Source, error) {
+	tr := tar.NewReader(r)
+	var sources []*Source
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		isPackage := strings.HasSuffix(header.Name, fileExt) && !IsArchiveName(header.Name)
+		if !isPackage && !IsArchiveName(header.Name) {
+			continue
+		}
+
+		entryName := name + "!" + header.Name
+		entryPath := path.Join(filepath.ToSlash(base), header.Name)
+		entry := prefix + header.Name
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", entryName, err)
+		}
+
+		if isPackage {
+			sources = append(sources, fromBytes(entryName, entryPath, entry, data))
+			continue
+		}
+		nested, err := expandNested(entryName, entryPath, entry+"/", data, fileExt)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, nested...)
+	}
+	return sources, nil
+}
+
+// expandNested 展开归档中嵌套的归档
+func expandNested(name, base, prefix string, data []byte, fileExt string) ([]*Source, error) {
+	kind := detectArchive(data)
+	if kind == "" {
+		return nil, nil
+	}
+	return expandArchive(kind, name, base, prefix, bytes.NewReader(data), int64(len(data)), fileExt)
+}
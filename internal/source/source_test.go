@@ -0,0 +1,308 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testExt = ".wxapkg"
+
+// entry 归档中的文件，store 为 true 时 zip 中不压缩
+type entry struct {
+	name  string
+	data  string
+	store bool
+}
+
+func zipData(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		method := zip.Deflate
+		if e.store {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarData(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// collected 输入源的名称、路径和内容
+type collected struct {
+	Name, Path, Entry, Data string
+}
+
+func readSources(t *testing.T, sources []*Source) []collected {
+	t.Helper()
+	var got []collected
+	for _, src := range sources {
+		f, err := src.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", src.Name, err)
+		}
+		data, err := io.ReadAll(io.NewSectionReader(f, 0, src.Size))
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("read %s error = %v", src.Name, err)
+		}
+		got = append(got, collected{src.Name, src.Path, src.Entry, string(data)})
+	}
+	return got
+}
+
+func TestCollectZip(t *testing.T) {
+	dir := t.TempDir()
+	archive := writeFile(t, filepath.Join(dir, "pkgs.zip"), zipData(t, []entry{
+		{name: "a/__APP__.wxapkg", data: "stored", store: true},
+		{name: "b/__APP__.wxapkg", data: "deflated"},
+		{name: "a/", store: true},
+		{name: "readme.txt", data: "skip"},
+	}))
+
+	sources, err := Collect(archive, testExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []collected{
+		{archive + "!a/__APP__.wxapkg", filepath.ToSlash(archive) + "/a/__APP__.wxapkg", "a/__APP__.wxapkg", "stored"},
+		{archive + "!b/__APP__.wxapkg", filepath.ToSlash(archive) + "/b/__APP__.wxapkg", "b/__APP__.wxapkg", "deflated"},
+	}
+	if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect = %+v, want %+v", got, want)
+	}
+
+	// 未压缩的包直接从归档文件中读取，不读入内存
+	f, err := sources[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, ok := f.(sectionFile); !ok {
+		t.Fatalf("stored entry opened as %T, want sectionFile", f)
+	}
+	buf := make([]byte, 3)
+	if _, err := f.ReadAt(buf, 2); err != nil || string(buf) != "ore" {
+		t.Errorf("ReadAt(2) = %q, %v, want \"ore\"", buf, err)
+	}
+}
+
+func TestOpenStoredZipEntryMissing(t *testing.T) {
+	archive := writeFile(t, filepath.Join(t.TempDir(), "pkgs.zip"), zipData(t, []entry{
+		{name: "a.wxapkg", data: "a", store: true},
+	}))
+	open := openStoredZipEntry(archive, &Source{Name: archive + "!b.wxapkg", Entry: "b.wxapkg"})
+	if f, err := open(); err == nil {
+		_ = f.Close()
+		t.Errorf("open missing entry error = nil")
+	}
+}
+
+func TestCollectTar(t *testing.T) {
+	entries := []entry{
+		{name: "wx1234/__APP__.wxapkg", data: "app"},
+		{name: "wx1234/sub.wxapkg", data: "sub"},
+		{name: "notes.txt", data: "skip"},
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"pkgs.tar", "pkgs.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			data := tarData(t, entries)
+			if name == "pkgs.tar.gz" {
+				data = gzipData(t, data)
+			}
+			archive := writeFile(t, filepath.Join(dir, name), data)
+
+			sources, err := Collect(archive, testExt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			base := filepath.ToSlash(archive)
+			want := []collected{
+				{archive + "!wx1234/__APP__.wxapkg", base + "/wx1234/__APP__.wxapkg", "wx1234/__APP__.wxapkg", "app"},
+				{archive + "!wx1234/sub.wxapkg", base + "/wx1234/sub.wxapkg", "wx1234/sub.wxapkg", "sub"},
+			}
+			if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+				t.Errorf("Collect = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCollectNested(t *testing.T) {
+	inner := gzipData(t, tarData(t, []entry{{name: "x/__APP__.wxapkg", data: "tgz"}}))
+	middle := zipData(t, []entry{
+		{name: "inner.tgz", data: string(inner), store: true},
+		{name: "m.wxapkg", data: "zip", store: true},
+	})
+	outer := tarData(t, []entry{
+		{name: "dir/middle.zip", data: string(middle)},
+		{name: "fake.zip", data: "not an archive"},
+	})
+	archive := writeFile(t, filepath.Join(t.TempDir(), "outer.tar"), outer)
+
+	sources, err := Collect(archive, testExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.ToSlash(archive)
+	want := []collected{
+		{archive + "!dir/middle.zip!inner.tgz!x/__APP__.wxapkg", base + "/dir/middle.zip/inner.tgz/x/__APP__.wxapkg", "dir/middle.zip/inner.tgz/x/__APP__.wxapkg", "tgz"},
+		{archive + "!dir/middle.zip!m.wxapkg", base + "/dir/middle.zip/m.wxapkg", "dir/middle.zip/m.wxapkg", "zip"},
+	}
+	if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollectDir(t *testing.T) {
+	dir := t.TempDir()
+	pkg := writeFile(t, filepath.Join(dir, "a.wxapkg"), []byte("pkg"))
+	archive := writeFile(t, filepath.Join(dir, "b.zip"), zipData(t, []entry{{name: "b.wxapkg", data: "b"}}))
+	writeFile(t, filepath.Join(dir, "c.txt"), []byte("skip"))
+	if err := os.Mkdir(filepath.Join(dir, "d.wxapkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := Collect(dir, testExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []collected{
+		{pkg, pkg, "", "pkg"},
+		{archive + "!b.wxapkg", filepath.ToSlash(archive) + "/b.wxapkg", "b.wxapkg", "b"},
+	}
+	if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect(dir) = %+v, want %+v", got, want)
+	}
+
+	// 逗号分隔的文件列表，忽略不存在的文件
+	sources, err = Collect(pkg+","+filepath.Join(dir, "missing.wxapkg")+","+archive, testExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect(list) = %+v, want %+v", got, want)
+	}
+}
+
+// withStdin 将标准输入替换为指定内容
+func withStdin(t *testing.T, data []byte) {
+	t.Helper()
+	f, err := os.Open(writeFile(t, filepath.Join(t.TempDir(), "stdin"), data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		_ = f.Close()
+	})
+}
+
+func TestCollectStdin(t *testing.T) {
+	t.Run("package", func(t *testing.T) {
+		withStdin(t, []byte("pkg"))
+		sources, err := Collect(Stdin, testExt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []collected{{Stdin, "stdin.wxapkg", "", "pkg"}}
+		if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect(-) = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		withStdin(t, zipData(t, []entry{{name: "a/__APP__.wxapkg", data: "stored", store: true}}))
+		sources, err := Collect(Stdin, testExt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 标准输入中未压缩的包同样读入内存
+		want := []collected{{"stdin!a/__APP__.wxapkg", "stdin/a/__APP__.wxapkg", "a/__APP__.wxapkg", "stored"}}
+		if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect(-) = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		withStdin(t, gzipData(t, tarData(t, []entry{{name: "p.wxapkg", data: "tgz"}})))
+		sources, err := Collect(Stdin, testExt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []collected{{"stdin!p.wxapkg", "stdin/p.wxapkg", "p.wxapkg", "tgz"}}
+		if got := readSources(t, sources); !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect(-) = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestIsArchiveName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"a.zip": true, "a.ZIP": true, "a.tar": true, "a.tar.gz": true, "a.TGZ": true,
+		"a.wxapkg": false, "a.gz": false, "zip": false,
+	} {
+		if got := IsArchiveName(name); got != want {
+			t.Errorf("IsArchiveName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package unpack
+
+import (
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
+)
+
+var (
+	// ErrTruncated 文件长度不足，数据被截断
+	ErrTruncated = wxapkg.ErrTruncated
+	// ErrBadMagic 文件头标记不正确
+	ErrBadMagic = wxapkg.ErrBadMagic
+	// ErrIndexOverflow 索引中的偏移量或长度超出有效范围
+	ErrIndexOverflow = wxapkg.ErrIndexOverflow
+)
+
+// IndexOverflowError 描述越界的索引项及其偏移量
+type IndexOverflowError = wxapkg.IndexOverflowError
@@ -0,0 +1,105 @@
+package unpack
+
+import (
+	"path"
+	"strings"
+
+	"github.com/Ackites/KillWxapkg/internal/config"
+)
+
+// Options 解包选项
+type Options struct {
+	Include []string        // 仅解包匹配的文件，为空时解包全部文件
+	Exclude []string        // 不解包匹配的文件，优先于 Include
+	Salvage bool            // 宽松模式，尽可能解包损坏或被截断的包
+	Session *config.Session // 解包使用的会话，为空时使用默认会话
+}
+
+// CurrentSession 返回解包使用的会话
+func (o *Options) CurrentSession() *config.Session {
+	if o == nil || o.Session == nil {
+		return config.DefaultSession()
+	}
+	return o.Session
+}
+
+// Selected 判断包内文件是否需要解包
+func (o *Options) Selected(name string) bool {
+	if o == nil {
+		return true
+	}
+	for _, pattern := range o.Exclude {
+		if MatchGlob(pattern, name) {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, pattern := range o.Include {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// salvage 是否启用宽松模式
+func (o *Options) salvage() bool {
+	return o != nil && o.Salvage
+}
+
+// ParseGlobs 解析逗号分隔的通配符列表
+func ParseGlobs(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// MatchGlob 判断包内文件名是否匹配通配符
+// 支持 *、?、[...] 及跨目录的 **，不含 / 的模式仅匹配文件名，如 *.json 匹配任意目录下的 json 文件，
+// 以 / 结尾的模式匹配该目录下的所有文件
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.ReplaceAll(pattern, "\\", "/")
+	// 以 / 结尾的模式表示目录，匹配其下所有文件
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	pattern = strings.Trim(pattern, "/")
+	name = strings.TrimLeft(name, "/")
+	if pattern == "" {
+		return false
+	}
+
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments 逐级匹配路径，** 可匹配零级或多级目录
+func matchSegments(patterns, parts []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(patterns[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], parts[0]); !ok {
+			return false
+		}
+		patterns, parts = patterns[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
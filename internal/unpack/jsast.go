@@ -0,0 +1,185 @@
+package unpack
+
+import (
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+)
+
+// jsSource 解析后的 JavaScript 代码，用于按语法树提取编译产物中的片段
+type jsSource struct {
+	code    string
+	program *ast.Program
+}
+
+// parseJs 解析 JavaScript 代码
+func parseJs(code string) (*jsSource, error) {
+	program, err := parser.ParseFile(nil, "", code, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &jsSource{code: code, program: program}, nil
+}
+
+// text 返回节点对应的源代码
+func (s *jsSource) text(node ast.Node) string {
+	return s.slice(int(node.Idx0()), int(node.Idx1()))
+}
+
+// slice 返回 [from, to) 范围内的源代码，位置从 1 开始
+func (s *jsSource) slice(from, to int) string {
+	base := s.program.File.Base()
+	from, to = from-base, to-base
+	if from < 0 || to > len(s.code) || from > to {
+		return ""
+	}
+	return s.code[from:to]
+}
+
+// eachBody 依次访问顶层代码及所有函数体的语句列表
+func (s *jsSource) eachBody(fn func(list []ast.Statement)) {
+	fn(s.program.Body)
+	for _, statement := range s.program.Body {
+		walkStatement(statement, fn)
+	}
+}
+
+// walkStatement 查找语句中的函数，访问其函数体
+func walkStatement(statement ast.Statement, fn func(list []ast.Statement)) {
+	switch st := statement.(type) {
+	case *ast.BlockStatement:
+		for _, child := range st.List {
+			walkStatement(child, fn)
+		}
+	case *ast.ExpressionStatement:
+		walkExpression(st.Expression, fn)
+	case *ast.VariableStatement:
+		for _, binding := range st.List {
+			walkExpression(binding.Initializer, fn)
+		}
+	case *ast.LexicalDeclaration:
+		for _, binding := range st.List {
+			walkExpression(binding.Initializer, fn)
+		}
+	case *ast.FunctionDeclaration:
+		walkExpression(st.Function, fn)
+	case *ast.IfStatement:
+		walkExpression(st.Test, fn)
+		walkStatement(st.Consequent, fn)
+		walkStatement(st.Alternate, fn)
+	case *ast.ReturnStatement:
+		walkExpression(st.Argument, fn)
+	case *ast.ThrowStatement:
+		walkExpression(st.Argument, fn)
+	case *ast.TryStatement:
+		walkStatement(st.Body, fn)
+		if st.Catch != nil {
+			walkStatement(st.Catch.Body, fn)
+		}
+		if st.Finally != nil {
+			walkStatement(st.Finally, fn)
+		}
+	case *ast.ForStatement:
+		walkStatement(st.Body, fn)
+	case *ast.ForInStatement:
+		walkStatement(st.Body, fn)
+	case *ast.WhileStatement:
+		walkStatement(st.Body, fn)
+	case *ast.DoWhileStatement:
+		walkStatement(st.Body, fn)
+	case *ast.LabelledStatement:
+		walkStatement(st.Statement, fn)
+	case *ast.SwitchStatement:
+		for _, c := range st.Body {
+			for _, child := range c.Consequent {
+				walkStatement(child, fn)
+			}
+		}
+	}
+}
+
+// walkExpression 查找表达式中的函数，访问其函数体
+func walkExpression(expression ast.Expression, fn func(list []ast.Statement)) {
+	switch ex := expression.(type) {
+	case *ast.FunctionLiteral:
+		if ex.Body != nil {
+			fn(ex.Body.List)
+			walkStatement(ex.Body, fn)
+		}
+	case *ast.AssignExpression:
+		walkExpression(ex.Left, fn)
+		walkExpression(ex.Right, fn)
+	case *ast.BinaryExpression:
+		walkExpression(ex.Left, fn)
+		walkExpression(ex.Right, fn)
+	case *ast.ConditionalExpression:
+		walkExpression(ex.Test, fn)
+		walkExpression(ex.Consequent, fn)
+		walkExpression(ex.Alternate, fn)
+	case *ast.CallExpression:
+		walkExpression(ex.Callee, fn)
+		for _, argument := range ex.ArgumentList {
+			walkExpression(argument, fn)
+		}
+	case *ast.NewExpression:
+		walkExpression(ex.Callee, fn)
+		for _, argument := range ex.ArgumentList {
+			walkExpression(argument, fn)
+		}
+	case *ast.SequenceExpression:
+		for _, child := range ex.Sequence {
+			walkExpression(child, fn)
+		}
+	case *ast.UnaryExpression:
+		walkExpression(ex.Operand, fn)
+	case *ast.DotExpression:
+		walkExpression(ex.Left, fn)
+	case *ast.BracketExpression:
+		walkExpression(ex.Left, fn)
+		walkExpression(ex.Member, fn)
+	case *ast.ArrayLiteral:
+		for _, child := range ex.Value {
+			walkExpression(child, fn)
+		}
+	case *ast.ObjectLiteral:
+		for _, property := range ex.Value {
+			if keyed, ok := property.(*ast.PropertyKeyed); ok {
+				walkExpression(keyed.Value, fn)
+			}
+		}
+	}
+}
+
+// identName 返回标识符的名称，不是标识符时返回空
+func identName(expression ast.Expression) string {
+	if ident, ok := expression.(*ast.Identifier); ok && ident != nil {
+		return ident.Name.String()
+	}
+	return ""
+}
+
+// stringValue 返回字符串字面量的值
+func stringValue(expression ast.Expression) (string, bool) {
+	if literal, ok := expression.(*ast.StringLiteral); ok {
+		return literal.Value.String(), true
+	}
+	return "", false
+}
+
+// callOf 返回以标识符调用的函数名及调用表达式，不是此类调用时返回空
+func callOf(expression ast.Expression) (string, *ast.CallExpression) {
+	call, ok := expression.(*ast.CallExpression)
+	if !ok {
+		return "", nil
+	}
+	return identName(call.Callee), call
+}
+
+// isLogicalOr 是否为 a || b 形式的表达式
+func isLogicalOr(expression ast.Expression) (*ast.BinaryExpression, bool) {
+	binary, ok := expression.(*ast.BinaryExpression)
+	if !ok || binary.Operator != token.LOGICAL_OR {
+		return nil, false
+	}
+	return binary, true
+}
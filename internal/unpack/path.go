@@ -0,0 +1,138 @@
+package unpack
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Rename 记录被重命名或拒绝的文件
+type Rename struct {
+	Original  string   `json:"original"`
+	Sanitized string   `json:"sanitized,omitempty"`
+	Reasons   []string `json:"reasons"`
+}
+
+// Windows 保留的设备名，带扩展名时同样不可用
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeName 将包内文件名转换为可移植的相对路径（使用 / 分隔）
+// 返回转换后的路径及重命名原因，无法得到有效路径时返回错误
+func SanitizeName(name string) (string, []string, error) {
+	var reasons []string
+	addReason := func(reason string) {
+		for _, r := range reasons {
+			if r == reason {
+				return
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if strings.ContainsRune(name, 0) {
+		name = strings.ReplaceAll(name, "\x00", "_")
+		addReason("包含NUL字符")
+	}
+	if strings.Contains(name, "\\") {
+		name = strings.ReplaceAll(name, "\\", "/")
+		addReason("包含反斜杠")
+	}
+
+	// 包内文件名均以 / 开头，此外的盘符和 UNC 路径视为绝对路径
+	if strings.HasPrefix(name, "//") || (len(name) >= 2 && name[1] == ':' && isLetter(name[0])) {
+		name = strings.TrimLeft(name[strings.Index(name, "/")+1:], "/")
+		addReason("绝对路径")
+	}
+
+	// 在虚拟根目录下解析 .. ，保证结果不会越过输出目录
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			addReason("包含上级目录引用")
+			break
+		}
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	var parts []string
+	for _, part := range strings.Split(cleaned, "/") {
+		if part == "" {
+			continue
+		}
+		portable, partReasons := portableComponent(part)
+		for _, r := range partReasons {
+			addReason(r)
+		}
+		parts = append(parts, portable)
+	}
+
+	if len(parts) == 0 {
+		return "", reasons, fmt.Errorf("文件名 %q 无有效路径", name)
+	}
+	return strings.Join(parts, "/"), reasons, nil
+}
+
+// portableComponent 处理单级路径中在常见文件系统上不可用的字符和名称
+func portableComponent(part string) (string, []string) {
+	var reasons []string
+
+	// 与原有处理保持一致，直接去除冒号
+	if strings.Contains(part, ":") {
+		part = strings.ReplaceAll(part, ":", "")
+		reasons = append(reasons, "包含冒号")
+	}
+
+	replaced := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, part)
+	if replaced != part {
+		part = replaced
+		reasons = append(reasons, "包含非法字符")
+	}
+
+	// Windows 不允许以点或空格结尾
+	if trimmed := strings.TrimRight(part, ". "); trimmed != part {
+		part = trimmed
+		reasons = append(reasons, "以点或空格结尾")
+	}
+	if part == "" {
+		part = "_"
+	}
+
+	base := part
+	if idx := strings.Index(base, "."); idx != -1 {
+		base = base[:idx]
+	}
+	if reservedNames[strings.ToUpper(base)] {
+		part = "_" + part
+		reasons = append(reasons, "系统保留名称")
+	}
+
+	return part, reasons
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// SafeJoin 将包内文件名安全地拼接到根目录下，结果保证位于根目录内
+func SafeJoin(root, name string) (string, []string, error) {
+	sanitized, reasons, err := SanitizeName(name)
+	if err != nil {
+		return "", reasons, err
+	}
+
+	target := filepath.Join(root, filepath.FromSlash(sanitized))
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", reasons, fmt.Errorf("文件名 %q 超出输出目录", name)
+	}
+	return target, reasons, nil
+}
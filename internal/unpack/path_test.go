@@ -0,0 +1,165 @@
+package unpack
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		reasons []string
+		wantErr bool
+	}{
+		{name: "/app.js", want: "app.js"},
+		{name: "/pages/index/index.wxml", want: "pages/index/index.wxml"},
+		{name: "pages//a.js", want: "pages/a.js"},
+
+		// 上级目录引用
+		{name: "/../../etc/passwd", want: "etc/passwd", reasons: []string{"包含上级目录引用"}},
+		{name: "/a/../../b.js", want: "b.js", reasons: []string{"包含上级目录引用"}},
+		{name: `\..\..\a.js`, want: "a.js", reasons: []string{"包含反斜杠", "包含上级目录引用"}},
+		{name: "/..", reasons: []string{"包含上级目录引用"}, wantErr: true},
+		{name: "/a/..", reasons: []string{"包含上级目录引用"}, wantErr: true},
+		{name: "/a/./b.js", want: "a/b.js"},
+
+		// 绝对路径、盘符及 UNC 路径
+		{name: "C:/Windows/a.js", want: "Windows/a.js", reasons: []string{"绝对路径"}},
+		{name: `C:\Windows\a.js`, want: "Windows/a.js", reasons: []string{"包含反斜杠", "绝对路径"}},
+		{name: `d:a.js`, want: "da.js", reasons: []string{"绝对路径", "包含冒号"}},
+		{name: "//server/share/a.js", want: "server/share/a.js", reasons: []string{"绝对路径"}},
+		{name: `\\server\share\a.js`, want: "server/share/a.js", reasons: []string{"包含反斜杠", "绝对路径"}},
+		{name: "//", reasons: []string{"绝对路径"}, wantErr: true},
+
+		// NUL 及其他非法字符
+		{name: "/a\x00b.js", want: "a_b.js", reasons: []string{"包含NUL字符"}},
+		{name: "/\x00", want: "_", reasons: []string{"包含NUL字符"}},
+		{name: "/a<b>|?*\".js", want: "a_b_____.js", reasons: []string{"包含非法字符"}},
+		{name: "/a\tb.js", want: "a_b.js", reasons: []string{"包含非法字符"}},
+		{name: "/a:b.js", want: "ab.js", reasons: []string{"包含冒号"}},
+
+		// Windows 保留名称
+		{name: "/con", want: "_con", reasons: []string{"系统保留名称"}},
+		{name: "/NUL.txt", want: "_NUL.txt", reasons: []string{"系统保留名称"}},
+		{name: "/lpt9.tar.gz", want: "_lpt9.tar.gz", reasons: []string{"系统保留名称"}},
+		{name: "/com1/a.js", want: "_com1/a.js", reasons: []string{"系统保留名称"}},
+		{name: "/console.js", want: "console.js"},
+		{name: "/com10", want: "com10"},
+
+		// 以点或空格结尾
+		{name: "/a.js.", want: "a.js", reasons: []string{"以点或空格结尾"}},
+		{name: "/a.js  ", want: "a.js", reasons: []string{"以点或空格结尾"}},
+		{name: "/dir. /a.js", want: "dir/a.js", reasons: []string{"以点或空格结尾"}},
+		{name: "/...", want: "_", reasons: []string{"以点或空格结尾"}},
+		{name: "/aux. ", want: "_aux", reasons: []string{"以点或空格结尾", "系统保留名称"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reasons, err := SanitizeName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SanitizeName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SanitizeName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("SanitizeName(%q) reasons = %q, want %q", tt.name, reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "/app.js", want: "app.js"},
+		{name: "/../../../etc/passwd", want: "etc/passwd"},
+		{name: `..\..\a.js`, want: "a.js"},
+		{name: "C:/Windows/win.ini", want: "Windows/win.ini"},
+		{name: `\\server\share\a.js`, want: "server/share/a.js"},
+		{name: "/nul", want: "_nul"},
+		{name: "/a\x00/b.js", want: "a_/b.js"},
+		{name: "/../..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := SafeJoin(root, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SafeJoin(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if want := filepath.Join(root, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("SafeJoin(%q) = %q, want %q", tt.name, got, want)
+			}
+			if rel, err := filepath.Rel(root, got); err != nil || strings.HasPrefix(rel, "..") {
+				t.Errorf("SafeJoin(%q) = %q escapes %q", tt.name, got, root)
+			}
+		})
+	}
+}
+
+func TestPlanExtraction(t *testing.T) {
+	files := []WxapkgFile{
+		{Name: "/a.js"},
+		{Name: "/A.js"},
+		{Name: "/a~1.js"},
+		{Name: "/Pages/Index.wxml"},
+		{Name: "/pages/index.wxml"},
+		{Name: "/pages/index.wxml."},
+		{Name: "/README"},
+		{Name: "/readme"},
+		{Name: "/.."},
+		{Name: "/b.js"},
+		{Name: "/b.js"},
+	}
+	report := &Report{}
+	tasks := planExtraction(files, report)
+
+	var names []string
+	for _, task := range tasks {
+		names = append(names, task.name)
+	}
+	want := []string{
+		"a.js",
+		"A~1.js",
+		"a~1~1.js",
+		"Pages/Index.wxml",
+		"pages/index~1.wxml",
+		"pages/index~2.wxml",
+		"README",
+		"readme~1",
+		"b.js",
+		"b.js",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("planExtraction names = %q, want %q", names, want)
+	}
+
+	renamed := make(map[string]Rename)
+	for _, r := range report.Renamed {
+		renamed[r.Original] = r
+	}
+	if r := renamed["/A.js"]; r.Sanitized != "A~1.js" || !reflect.DeepEqual(r.Reasons, []string{"与其他文件路径冲突"}) {
+		t.Errorf("renamed /A.js = %+v", r)
+	}
+	if r := renamed["/pages/index.wxml."]; !reflect.DeepEqual(r.Reasons, []string{"以点或空格结尾", "与其他文件路径冲突"}) {
+		t.Errorf("renamed /pages/index.wxml. = %+v", r)
+	}
+	if _, ok := renamed["/b.js"]; ok {
+		t.Errorf("duplicate entries of the same name should not be renamed")
+	}
+	if len(report.Rejected) != 1 || report.Rejected[0].Original != "/.." {
+		t.Errorf("rejected = %+v, want /..", report.Rejected)
+	}
+}
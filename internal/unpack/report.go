@@ -0,0 +1,102 @@
+package unpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Ackites/KillWxapkg/internal/sink"
+)
+
+// 宽松模式下文件的恢复状态
+const (
+	RecoveryOK        = "ok"        // 完整解包
+	RecoveryTruncated = "truncated" // 数据不完整，已保存现有部分
+	RecoverySkipped   = "skipped"   // 数据完全缺失，已跳过
+	RecoveryFailed    = "failed"    // 读取或写入失败
+)
+
+// Report 解包报告
+type Report struct {
+	Files       []string   `json:"-"`                     // 包内全部文件名，用于判断包类型
+	Renamed     []Rename   `json:"renamed,omitempty"`     // 因路径不安全或不可移植而重命名的文件
+	Rejected    []Rename   `json:"rejected,omitempty"`    // 无法得到有效路径而跳过的文件
+	IndexErrors []string   `json:"indexErrors,omitempty"` // 宽松模式下索引段中发现的问题
+	Recovery    []Recovery `json:"recovery,omitempty"`    // 宽松模式下每个文件的恢复情况
+}
+
+// Recovery 宽松模式下单个文件的恢复情况
+type Recovery struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Offset  uint32 `json:"offset"`
+	Size    uint32 `json:"size"`
+	Written int64  `json:"written"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// HasIssues 报告中是否有需要记录的内容
+func (r *Report) HasIssues() bool {
+	return len(r.Renamed) > 0 || len(r.Rejected) > 0 || len(r.IndexErrors) > 0 || len(r.Recovery) > 0
+}
+
+// Recovered 统计各恢复状态的文件数量
+func (r *Report) Recovered() map[string]int {
+	counts := make(map[string]int)
+	for _, recovery := range r.Recovery {
+		counts[recovery.Status]++
+	}
+	return counts
+}
+
+// Save 以 JSON 格式将报告保存到输出目标
+func (r *Report) Save(output sink.Sink, filename string) error {
+	content, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return err
+	}
+	return output.WriteFile(filename, content)
+}
+
+// extractTask 待写入的文件及其目标相对路径
+type extractTask struct {
+	file WxapkgFile
+	name string
+	size int64 // 实际读取的长度，宽松模式下可能小于 file.Size
+}
+
+// planExtraction 为每个索引项确定安全的目标路径，并记录重命名和跳过的文件
+// 不同文件清理后得到相同路径（忽略大小写）时追加序号，避免相互覆盖
+func planExtraction(fileList []WxapkgFile, report *Report) []extractTask {
+	tasks := make([]extractTask, 0, len(fileList))
+	used := make(map[string]string)
+
+	for _, file := range fileList {
+		name, reasons, err := SanitizeName(file.Name)
+		if err != nil {
+			report.Rejected = append(report.Rejected, Rename{Original: file.Name, Reasons: append(reasons, err.Error())})
+			continue
+		}
+
+		if original, ok := used[strings.ToLower(name)]; ok && original != file.Name {
+			ext := path.Ext(name)
+			for i := 1; ; i++ {
+				candidate := fmt.Sprintf("%s~%d%s", strings.TrimSuffix(name, ext), i, ext)
+				if _, exists := used[strings.ToLower(candidate)]; !exists {
+					name = candidate
+					break
+				}
+			}
+			reasons = append(reasons, "与其他文件路径冲突")
+		}
+		used[strings.ToLower(name)] = file.Name
+
+		if len(reasons) > 0 {
+			report.Renamed = append(report.Renamed, Rename{Original: file.Name, Sanitized: name, Reasons: reasons})
+		}
+		tasks = append(tasks, extractTask{file: file, name: name, size: int64(file.Size)})
+	}
+
+	return tasks
+}
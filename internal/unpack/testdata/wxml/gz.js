@@ -0,0 +1,131 @@
+var __wxAppCode__=__wxAppCode__||{};
+var __wcc_version__='v0.5vv_20211229_syscb';
+var $gwx=function(path,global){
+if(typeof global === 'undefined') global={};if(typeof __WXML_GLOBAL__ === 'undefined') {__WXML_GLOBAL__={};
+}__WXML_GLOBAL__.modules = __WXML_GLOBAL__.modules || {};
+var e_={};var d_={};var p_={};var f_={};
+function gz$gwx_1(){
+if( __WXML_GLOBAL__.ops_cached.$gwx_1)return __WXML_GLOBAL__.ops_cached.$gwx_1
+__WXML_GLOBAL__.ops_cached.$gwx_1=[];
+(function(z){var a=11;function Z(ops){z.push(ops)}
+Z([a,[3,'page '],[[7],[3,'theme']]])
+Z([[7],[3,'id']])
+Z([[2,'+'],[[7],[3,'a']],[[2,'*'],[[7],[3,'b']],[[7],[3,'c']]]])
+Z([[2,'*'],[[2,'+'],[[7],[3,'a']],[[7],[3,'b']]],[[7],[3,'c']]])
+Z([[2,'-'],[[7],[3,'a']],[[2,'-'],[[7],[3,'b']],[[7],[3,'c']]]])
+Z([[2,'!'],[[2,'&&'],[[7],[3,'a']],[[7],[3,'b']]]])
+Z([[2,'?:'],[[7],[3,'a']],[[7],[3,'b']],[[2,'?:'],[[7],[3,'c']],[[7],[3,'d']],[[7],[3,'e']]]])
+Z([[2,'?:'],[[2,'?:'],[[7],[3,'a']],[[7],[3,'b']],[[7],[3,'c']]],[[7],[3,'d']],[[7],[3,'e']]])
+Z([[2,'-'],[[2,'+'],[[7],[3,'a']],[[7],[3,'b']]]])
+Z([[6],[[6],[[7],[3,'list']],[1,0]],[3,'name']])
+Z([[2,'||'],[[7],[3,'a']],[[2,'&&'],[[7],[3,'b']],[[7],[3,'c']]]])
+Z([[2,'&&'],[[2,'||'],[[7],[3,'a']],[[7],[3,'b']]],[[7],[3,'c']]])
+Z([[2,'>'],[[7],[3,'n']],[1,1]])
+Z([3,'many'])
+Z([[2,'==='],[[7],[3,'n']],[1,1]])
+Z([3,'one'])
+Z([3,'none'])
+Z([[7],[3,'items']])
+Z([a,[[7],[3,'i']],[3,': '],[[6],[[7],[3,'it']],[3,'name']]])
+Z([[4],[[5],[[5],[[5],[1,1]],[1,2]],[1,3]]])
+Z([[7],[3,'item']])
+})(__WXML_GLOBAL__.ops_cached.$gwx_1);return __WXML_GLOBAL__.ops_cached.$gwx_1
+}
+var x=['./pages/index/index.wxml'];d_[x[0]]={}
+var m0=function(e,s,r,gg){
+var z=gz$gwx_1()
+var oB=_mz(z,'view',['class',0,'data-id',1],[],e,s,gg)
+var xC=_n('text')
+var oD=_oz(z,2,e,s,gg)
+_(xC,oD)
+_(oB,xC)
+var fE=_n('text')
+var cF=_oz(z,3,e,s,gg)
+_(fE,cF)
+_(oB,fE)
+var hG=_n('text')
+var oH=_oz(z,4,e,s,gg)
+_(hG,oH)
+_(oB,hG)
+var cI=_n('text')
+var oJ=_oz(z,5,e,s,gg)
+_(cI,oJ)
+_(oB,cI)
+var lK=_n('text')
+var aL=_oz(z,6,e,s,gg)
+_(lK,aL)
+_(oB,lK)
+var tM=_n('text')
+var eN=_oz(z,7,e,s,gg)
+_(tM,eN)
+_(oB,tM)
+var bO=_n('text')
+var oP=_oz(z,8,e,s,gg)
+_(bO,oP)
+_(oB,bO)
+var xQ=_n('text')
+var oR=_oz(z,9,e,s,gg)
+_(xQ,oR)
+_(oB,xQ)
+var fS=_n('text')
+var cT=_oz(z,10,e,s,gg)
+_(fS,cT)
+_(oB,fS)
+var hU=_n('text')
+var oV=_oz(z,11,e,s,gg)
+_(hU,oV)
+_(oB,hU)
+var cW=_v()
+_(oB,cW)
+if(_oz(z,12,e,s,gg)){cW.wxVkey=1
+var oX=_n('view')
+var lY=_oz(z,13,e,s,gg)
+_(oX,lY)
+_(cW,oX)
+}
+else if(_oz(z,14,e,s,gg)){cW.wxVkey=2
+var a1=_n('view')
+var t2=_oz(z,15,e,s,gg)
+_(a1,t2)
+_(cW,a1)
+}
+else{cW.wxVkey=3
+var e3=_n('view')
+var b4=_oz(z,16,e,s,gg)
+_(e3,b4)
+_(cW,e3)
+}
+var o5=_v()
+_(oB,o5)
+var x6=function(o7,f8,c9,gg){
+var o0B=_n('view')
+var cAB=_oz(z,18,o7,f8,gg)
+_(o0B,cAB)
+_(c9,o0B)
+return c9
+}
+o5.wxXCkey=2
+_2z(z,17,x6,e,s,gg,o5,'it','i','id')
+var oBB=_v()
+_(oB,oBB)
+var lCB=function(aDB,tEB,eFB,gg){
+var oHB=_n('view')
+var xIB=_oz(z,20,aDB,tEB,gg)
+_(oHB,xIB)
+_(eFB,oHB)
+return eFB
+}
+oBB.wxXCkey=2
+_2z(z,19,lCB,e,s,gg,oBB,'item','index','')
+_(r,oB)
+return r
+}
+e_[x[0]]={f:m0,j:[],i:[],ti:[],ic:[]}
+if(path&&e_[path]){
+return function(env,dd,global){$gwxc=0;var root={"tag":"wx-page"};root.children=[]
+var main=e_[path].f
+try{main(env,{},root,global);}catch(err){console.log(err)}
+return root;
+}
+}
+}
@@ -0,0 +1,135 @@
+var __wxAppCode__=__wxAppCode__||{};
+var __wcc_version__='v0.5vv_20211229_syscb';
+var $gwx=function(path,global){
+if(typeof global === 'undefined') global={};if(typeof __WXML_GLOBAL__ === 'undefined') {__WXML_GLOBAL__={};
+}__WXML_GLOBAL__.modules = __WXML_GLOBAL__.modules || {};
+var e_={};var d_={};var p_={};var f_={};
+function gz$gwx_1(){
+if( __WXML_GLOBAL__.ops_cached.$gwx_1)return __WXML_GLOBAL__.ops_cached.$gwx_1
+__WXML_GLOBAL__.ops_cached.$gwx_1=[];
+(function(z){var a=11;function Z(ops){z.push(ops)}
+Z([3,'ta'])
+Z([[10],[[7],[3,'item']]])
+})(__WXML_GLOBAL__.ops_cached.$gwx_1);return __WXML_GLOBAL__.ops_cached.$gwx_1
+}
+function gz$gwx_2(){
+if( __WXML_GLOBAL__.ops_cached.$gwx_2)return __WXML_GLOBAL__.ops_cached.$gwx_2
+__WXML_GLOBAL__.ops_cached.$gwx_2=[];
+(function(z){var a=11;function Z(ops){z.push(ops)}
+Z([[7],[3,'title']])
+Z([3,'tb'])
+Z([[8],'name',[[7],[3,'title']]])
+})(__WXML_GLOBAL__.ops_cached.$gwx_2);return __WXML_GLOBAL__.ops_cached.$gwx_2
+}
+function gz$gwx_3(){
+if( __WXML_GLOBAL__.ops_cached.$gwx_3)return __WXML_GLOBAL__.ops_cached.$gwx_3
+__WXML_GLOBAL__.ops_cached.$gwx_3=[];
+(function(z){var a=11;function Z(ops){z.push(ops)}
+Z([[7],[3,'name']])
+Z([3,'footer'])
+})(__WXML_GLOBAL__.ops_cached.$gwx_3);return __WXML_GLOBAL__.ops_cached.$gwx_3
+}
+var x=['./pages/index/index.wxml','./common/a.wxml','./components/b/b.wxml'];d_[x[0]]={}
+var m0=function(e,s,r,gg){
+var z=gz$gwx_1()
+var oB=e_[x[0]].i
+_ai(oB,x[1],e_,x[0],1,1)
+var xC=_v()
+_(r,xC)
+var oD=_oz(z,0,e,s,gg)
+var fE=_gd(x[0],oD,e_,d_)
+if(fE){
+var cF=_1z(z,1,e,s,gg) || {}
+var cur_globalf=gg.f
+xC.wxXCkey=3
+fE(cF,cF,xC,gg)
+gg.f=cur_globalf
+}
+else _w(oD,x[0],2,14)
+var hG=e_[x[0]].j
+_ic(x[2],e_,x[0],e,s,r,gg)
+hG.pop()
+oB.pop()
+return r
+}
+e_[x[0]]={f:m0,j:[],i:[],ti:[x[1]],ic:[x[2]]}
+d_[x[1]]={}
+d_[x[1]]["ta"]=function(e,s,r,gg){
+var z=gz$gwx_2()
+var b=x[1]+':ta'
+r.wxVkey=b
+gg.f=$gdc(f_["./common/a.wxml"],"",1)
+if(p_[b]){_wl(b,x[1]);return}
+p_[b]=true
+try{
+var oB=_n('view')
+var xC=_oz(z,0,e,s,gg)
+_(oB,xC)
+var oD=_v()
+_(oB,oD)
+var fE=_oz(z,1,e,s,gg)
+var cF=_gd(x[1],fE,e_,d_)
+if(cF){
+var hG=_1z(z,2,e,s,gg) || {}
+var cur_globalf=gg.f
+oD.wxXCkey=3
+cF(hG,hG,oD,gg)
+gg.f=cur_globalf
+}
+else _w(fE,x[1],3,6)
+_(r,oB)
+}catch(err){
+p_[b]=false
+throw err
+}
+p_[b]=false
+return r
+}
+var m1=function(e,s,r,gg){
+var z=gz$gwx_2()
+var oB=e_[x[1]].i
+_ai(oB,x[2],e_,x[1],1,1)
+oB.pop()
+return r
+}
+e_[x[1]]={f:m1,j:[],i:[],ti:[x[2]],ic:[]}
+d_[x[2]]={}
+d_[x[2]]["tb"]=function(e,s,r,gg){
+var z=gz$gwx_3()
+var b=x[2]+':tb'
+r.wxVkey=b
+gg.f=$gdc(f_["./components/b/b.wxml"],"",1)
+if(p_[b]){_wl(b,x[2]);return}
+p_[b]=true
+try{
+var oB=_n('text')
+var xC=_oz(z,0,e,s,gg)
+_(oB,xC)
+_(r,oB)
+}catch(err){
+p_[b]=false
+throw err
+}
+p_[b]=false
+return r
+}
+var m2=function(e,s,r,gg){
+var z=gz$gwx_3()
+var oB=e_[x[2]].i
+_ai(oB,x[1],e_,x[2],1,1)
+var xC=_n('view')
+var oD=_oz(z,1,e,s,gg)
+_(xC,oD)
+_(r,xC)
+oB.pop()
+return r
+}
+e_[x[2]]={f:m2,j:[],i:[],ti:[x[1]],ic:[]}
+if(path&&e_[path]){
+return function(env,dd,global){$gwxc=0;var root={"tag":"wx-page"};root.children=[]
+var main=e_[path].f
+try{main(env,{},root,global);}catch(err){console.log(err)}
+return root;
+}
+}
+}
@@ -0,0 +1,39 @@
+var __wxAppCode__=__wxAppCode__||{};
+var __wcc_version__='v0.5vv_20211229_syscb';
+var $gwx=function(path,global){
+if(typeof global === 'undefined') global={};if(typeof __WXML_GLOBAL__ === 'undefined') {__WXML_GLOBAL__={};
+}__WXML_GLOBAL__.modules = __WXML_GLOBAL__.modules || {};
+var e_={};var d_={};var p_={};var f_={};
+var nv_require=function(){var nnm={"m_./pages/index/index.wxml:tools":np_0,"p_./utils/format.wxs":np_1,"p_./common/pad.wxs":np_2,};var nom={};return function(n){if(n[0]==='p'&&n[1]==='_'&&f_[n.slice(2)])return f_[n.slice(2)];return function(){if(!nnm[n]) return undefined;try{if(!nom[n])nom[n]=nnm[n]();return nom[n];}catch(e){console.error(e);}}}}()
+f_['./pages/index/index.wxml']={};
+f_['./pages/index/index.wxml']['tools'] =nv_require("m_./pages/index/index.wxml:tools");
+function np_0(){var nv_module={nv_exports:{}};var nv_double = (function (nv_n){return(nv_n * 2)});nv_module.nv_exports = ({nv_double:nv_double,});return nv_module.nv_exports;}
+
+f_['./pages/index/index.wxml']['fmt'] =f_['./utils/format.wxs'] || nv_require("p_./utils/format.wxs");
+f_['./pages/index/index.wxml']['fmt']();
+
+f_['./common/pad.wxs'] = nv_require("p_./common/pad.wxs");
+function np_2(){var nv_module={nv_exports:{}};
+  // pad: nv_0 prefix is kept in comments
+  function nv_pad(nv_n) {
+    /* nv_n < 10 */
+    return nv_n < 10 ? 'nv_0' + nv_n : '' + nv_n;
+  };
+  nv_module.nv_exports = ({
+    nv_pad: nv_pad,
+  });
+return nv_module.nv_exports;}
+
+f_['./utils/format.wxs'] = nv_require("p_./utils/format.wxs");
+function np_1(){var nv_module={nv_exports:{}};
+  var nv_pad = nv_require('p_./common/pad.wxs')();
+  var nv_reg = /nv_\d+[/]/g;
+  function nv_fmt(nv_n) {
+    return nv_pad.nv_pad(nv_n / 2) + nv_reg.nv_source;
+  };
+  nv_module.nv_exports = ({
+    nv_fmt: nv_fmt,
+  });
+return nv_module.nv_exports;}
+
+}
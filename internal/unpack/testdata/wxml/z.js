@@ -0,0 +1,72 @@
+var __wxAppCode__=__wxAppCode__||{};
+var __wcc_version__='v0.5vv_20190312_syscb';
+var $gwx=function(path,global){
+if(typeof global === 'undefined') global={};if(typeof __WXML_GLOBAL__ === 'undefined') {__WXML_GLOBAL__={};
+}__WXML_GLOBAL__.modules = __WXML_GLOBAL__.modules || {};
+var e_={};var d_={};var p_={};var f_={};
+var z=__WXML_GLOBAL__.ops_set.$gwx || [];
+(function(z){var a=11;function Z(ops){z.push(ops)}
+Z([3,'box'])
+Z([[2,'!'],[[7],[3,'show']]])
+Z([a,[3,'color: '],[[7],[3,'color']]])
+Z([[7],[3,'msg']])
+Z([[2,'&&'],[[7],[3,'a']],[[2,'||'],[[7],[3,'b']],[[7],[3,'c']]]])
+Z([[2,'-'],[[7],[3,'n']],[[2,'-'],[[7],[3,'m']],[1,1]]])
+Z([[2,'?:'],[[2,'==='],[[2,'%'],[[7],[3,'x']],[1,2]],[1,0]],[1,'even'],[1,'odd']])
+Z([[7],[3,'rows']])
+Z([[6],[[7],[3,'row']],[3,'cells']])
+Z([a,[[7],[3,'j']],[[7],[3,'cell']]])
+Z([[2,'/'],[[2,'+'],[[7],[3,'a']],[[7],[3,'b']]],[1,2]])
+})(z);
+__WXML_GLOBAL__.ops_set.$gwx=z;
+var x=['./pages/index/index.wxml'];d_[x[0]]={}
+var m0=function(e,s,r,gg){
+var oB=_m('view',['class',0,'hidden',1,'style',1],[],e,s,gg)
+var xC=_o(3,e,s,gg)
+_(oB,xC)
+var oD=_v()
+_(oB,oD)
+if(_o(4,e,s,gg)){oD.wxVkey=1
+var fE=_o(5,e,s,gg)
+_(oD,fE)
+}
+else{oD.wxVkey=2
+var cF=_n('text')
+var hG=_o(6,e,s,gg)
+_(cF,hG)
+_(oD,cF)
+}
+var oH=_v()
+_(oB,oH)
+var cI=function(oJ,lK,aL,gg){
+var eN=_n('view')
+var bO=_v()
+_(eN,bO)
+var oP=function(xQ,oR,fS,gg){
+var hU=_n('text')
+var oV=_o(9,xQ,oR,gg)
+_(hU,oV)
+_(fS,hU)
+return fS
+}
+bO.wxXCkey=2
+_2(8,oP,oJ,lK,gg,bO,'cell','j','')
+_(aL,eN)
+return aL
+}
+oH.wxXCkey=2
+_2(7,cI,e,s,gg,oH,'row','index','*this')
+var cW=_m('input',['disabled',-1,'value',10],[],e,s,gg)
+_(oB,cW)
+_(r,oB)
+return r
+}
+e_[x[0]]={f:m0,j:[],i:[],ti:[],ic:[]}
+if(path&&e_[path]){
+return function(env,dd,global){$gwxc=0;var root={"tag":"wx-page"};root.children=[]
+var main=e_[path].f
+try{main(env,{},root,global);}catch(err){console.log(err)}
+return root;
+}
+}
+}
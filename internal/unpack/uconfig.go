@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -14,6 +13,7 @@ import (
 	"github.com/Ackites/KillWxapkg/internal/enum"
 
 	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
 
 	"github.com/dop251/goja"
 )
@@ -21,6 +21,7 @@ import (
 // ConfigParser 具体的配置文件解析器
 type ConfigParser struct {
 	OutputDir string
+	Output    sink.Sink // 输出目标，为空时直接写入磁盘
 }
 
 // PageConfig 存储页面配置
@@ -52,23 +53,25 @@ func changeExt(filename, newExt string) string {
 	return filename[:len(filename)-len(ext)] + newExt
 }
 
-// save 保存内容到文件
-func save(filename string, content []byte) error {
-	// 处理文件路径
-	filename = filepath.ToSlash(filename)
-	if idx := strings.Index(filename, ":"); idx != -1 {
-		filename = filename[:idx+1] + strings.ReplaceAll(filename[idx+1:], ":", "")
+// outputOf 返回解析器的输出目标，未设置时直接写入磁盘
+func outputOf(output sink.Sink) sink.Sink {
+	if output == nil {
+		return sink.NewDir("")
 	}
+	return output
+}
 
-	// 判断目录是否存在
-	dir := filepath.Dir(filename)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return fmt.Errorf("unable to create directory %s: %v", dir, err)
-		}
+// save 保存内容到根目录下的指定文件，文件名经过清理，不会写到根目录之外
+func save(output sink.Sink, root, name string, content []byte) error {
+	filename, reasons, err := SafeJoin(root, name)
+	if err != nil {
+		return err
 	}
-	err := os.WriteFile(filename, content, 0755)
+	if len(reasons) > 0 {
+		log.Printf("文件 %s 已重命名为 %s: %s\n", name, filename, strings.Join(reasons, ", "))
+	}
+
+	err = output.WriteFile(filename, content)
 	if err != nil {
 		return fmt.Errorf("unable to save file %s: %v", filename, err)
 	}
@@ -77,8 +80,9 @@ func save(filename string, content []byte) error {
 
 // Parse 解析和处理配置文件
 func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
+	output := outputOf(p.Output)
 	dir := filepath.Dir(option.Option.AppConfigSource)
-	content, err := os.ReadFile(option.Option.AppConfigSource)
+	content, err := output.ReadFile(option.Option.AppConfigSource)
 	if err != nil {
 		return err
 	}
@@ -174,7 +178,7 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 			"extAppid":  e.ExtAppid,
 			"ext":       e.Ext,
 		}, "", "    ")
-		err := save(filepath.Join(dir, "ext.json"), extContent)
+		err := save(output, dir, "ext.json", extContent)
 		if err != nil {
 			return err
 		}
@@ -208,8 +212,8 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 	}
 
 	// 处理 app-service.js 文件, 主包及子包
-	if fileExists(filepath.Join(dir, enum.App_Service)) {
-		serviceContent, _ := os.ReadFile(filepath.Join(dir, enum.App_Service))
+	if output.Exists(filepath.Join(dir, enum.App_Service)) {
+		serviceContent, _ := output.ReadFile(filepath.Join(dir, enum.App_Service))
 		matches := findMatches(`__wxAppCode__\['[^']+\.json'\]\s*=\s*({[^;]*});`, string(serviceContent))
 		if len(matches) > 0 {
 			attachInfo := make(map[string]interface{})
@@ -231,10 +235,10 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 		for _, subPackage := range app.SubPackages {
 			root := subPackage.Root
 			subServiceFile := filepath.Join(dir, root, enum.App_Service)
-			if !fileExists(subServiceFile) {
+			if !output.Exists(subServiceFile) {
 				continue
 			}
-			serviceContent, _ = os.ReadFile(subServiceFile)
+			serviceContent, _ = output.ReadFile(subServiceFile)
 			matches = findMatches(`__wxAppCode__\['[^']+\.json'\]\s*=\s*({[^;]*});`, string(serviceContent))
 			if len(matches) > 0 {
 				attachInfo := make(map[string]interface{})
@@ -260,7 +264,7 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 		fileName := filepath.Join(dir, aFile)
 		if aFile != "app.json" {
 			windowContent, _ := json.MarshalIndent(e.Page[a].Window, "", "    ")
-			err = save(fileName, windowContent)
+			err = save(output, dir, aFile, windowContent)
 			if err != nil {
 				log.Printf("Error saving file %s: %v\n", fileName, err)
 			}
@@ -272,15 +276,15 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 		for _, subPackage := range app.SubPackages {
 			for _, item := range subPackage.Pages {
 				a := subPackage.Root + item + ".xx"
-				err := save(filepath.Join(dir, changeExt(a, ".js")), []byte("// "+changeExt(a, ".js")+"\nPage({data: {}})"))
+				err := save(output, dir, changeExt(a, ".js"), []byte("// "+changeExt(a, ".js")+"\nPage({data: {}})"))
 				if err != nil {
 					return err
 				}
-				err = save(filepath.Join(dir, changeExt(a, ".wxml")), []byte("<!--"+changeExt(a, ".wxml")+"--><text>"+changeExt(a, ".wxml")+"</text>"))
+				err = save(output, dir, changeExt(a, ".wxml"), []byte("<!--"+changeExt(a, ".wxml")+"--><text>"+changeExt(a, ".wxml")+"</text>"))
 				if err != nil {
 					return err
 				}
-				err = save(filepath.Join(dir, changeExt(a, ".wxss")), []byte("/* "+changeExt(a, ".wxss")+" */"))
+				err = save(output, dir, changeExt(a, ".wxss"), []byte("/* "+changeExt(a, ".wxss")+" */"))
 				if err != nil {
 					return err
 				}
@@ -291,8 +295,8 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 	// 处理 TabBar 图标路径
 	if app.TabBar != nil && app.TabBar["list"] != nil {
 		var digests [][2]interface{}
-		for _, file := range scanDirByExt(dir, "") {
-			data, _ := os.ReadFile(file)
+		for _, file := range scanDirByExt(output, dir, "") {
+			data, _ := output.ReadFile(file)
 			digests = append(digests, [2]interface{}{md5.Sum(data), file})
 		}
 
@@ -326,7 +330,7 @@ func (p *ConfigParser) Parse(option config.WxapkgInfo) error {
 
 	// 保存应用配置到 app.json
 	appContent, _ := json.MarshalIndent(app, "", "    ")
-	err = save(filepath.Join(dir, "app.json"), appContent)
+	err = save(output, dir, "app.json", appContent)
 	if err != nil {
 		return err
 	}
@@ -344,15 +348,6 @@ func indexOf(slice []string, item string) int {
 	return -1
 }
 
-// fileExists 检查文件是否存在
-func fileExists(filename string) bool {
-	info, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false
-	}
-	return !info.IsDir()
-}
-
 // toDir 将文件路径转换为相对路径
 func toDir(file, base string) string {
 	relative, err := filepath.Rel(base, file)
@@ -369,10 +364,10 @@ func findMatches(pattern, text string) []string {
 }
 
 // scanDirByExt 扫描目录中的文件并返回指定扩展名的文件列表
-func scanDirByExt(dir, ext string) []string {
+func scanDirByExt(output sink.Sink, dir, ext string) []string {
 	var files []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ext) {
+	err := output.Walk(dir, func(path string) error {
+		if strings.HasSuffix(filepath.Base(path), ext) {
 			files = append(files, path)
 		}
 		return nil
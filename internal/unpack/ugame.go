@@ -0,0 +1,123 @@
+package unpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ackites/KillWxapkg/internal/enum"
+
+	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
+)
+
+// GameConfigParser 小游戏配置解析器，由 app-config.json 还原 game.json，并拆分开放数据域 subContext.js
+type GameConfigParser struct {
+	OutputDir string
+	Output    sink.Sink // 输出目标，为空时直接写入磁盘
+}
+
+// GameConfig 存储小游戏配置
+type GameConfig struct {
+	DeviceOrientation              string                 `json:"deviceOrientation,omitempty"`
+	ShowStatusBar                  bool                   `json:"showStatusBar,omitempty"`
+	NetworkTimeout                 map[string]interface{} `json:"networkTimeout,omitempty"`
+	Workers                        interface{}            `json:"workers,omitempty"`
+	SubPackages                    []GameSubPackage       `json:"subpackages,omitempty"`
+	OpenDataContext                string                 `json:"openDataContext,omitempty"`
+	Plugins                        map[string]interface{} `json:"plugins,omitempty"`
+	NavigateToMiniProgramAppIdList []string               `json:"navigateToMiniProgramAppIdList,omitempty"`
+	Permission                     map[string]interface{} `json:"permission,omitempty"`
+	Resizable                      bool                   `json:"resizable,omitempty"`
+}
+
+// GameSubPackage 存储小游戏分包配置
+type GameSubPackage struct {
+	Name        string `json:"name,omitempty"`
+	Root        string `json:"root"`
+	Independent bool   `json:"independent,omitempty"`
+}
+
+// Parse 还原 game.json 及开放数据域代码
+func (p *GameConfigParser) Parse(option config.WxapkgInfo) error {
+	output := outputOf(p.Output)
+	dir := filepath.Dir(option.Option.AppConfigSource)
+	content, err := output.ReadFile(option.Option.AppConfigSource)
+	if err != nil {
+		return err
+	}
+
+	// app-config.json 中的分包可能为 subpackages 或 subPackages
+	var e struct {
+		GameConfig
+		SubPackages      []GameSubPackage `json:"subpackages"`
+		SubPackagesCamel []GameSubPackage `json:"subPackages"`
+	}
+	err = json.Unmarshal(content, &e)
+	if err != nil {
+		return err
+	}
+
+	game := e.GameConfig
+	subPackages := append(e.SubPackages, e.SubPackagesCamel...)
+	for _, subPackage := range subPackages {
+		subPackage.Root = strings.TrimPrefix(subPackage.Root, "/")
+		if subPackage.Name == "" {
+			subPackage.Name = strings.TrimSuffix(subPackage.Root, "/")
+		}
+		game.SubPackages = append(game.SubPackages, subPackage)
+	}
+	if len(game.SubPackages) > 0 {
+		fmt.Printf("=======================================================\n这个小游戏采用了分包\n子包个数为: %d\n=======================================================\n", len(game.SubPackages))
+	}
+
+	// 拆分开放数据域
+	subContext := filepath.Join(dir, enum.SubContext)
+	if output.Exists(subContext) {
+		if game.OpenDataContext == "" {
+			game.OpenDataContext = enum.OpenDataContext
+		}
+		if err := p.splitSubContext(output, dir, subContext, game.OpenDataContext); err != nil {
+			log.Printf("Error splitting %s: %v\n", subContext, err)
+		}
+	}
+
+	// 保存游戏配置到 game.json
+	gameContent, _ := json.MarshalIndent(game, "", "    ")
+	err = save(output, dir, enum.GameJson, gameContent)
+	if err != nil {
+		return err
+	}
+	log.Printf("Config file processed: %s\n", option.Option.AppConfigSource)
+	return nil
+}
+
+// splitSubContext 将 subContext.js 中的模块拆分到开放数据域目录，模块名不含该目录时补全
+func (p *GameConfigParser) splitSubContext(output sink.Sink, dir, subContext, root string) error {
+	code, err := output.ReadFile(subContext)
+	if err != nil {
+		return err
+	}
+
+	params, err := extractDefineParams(string(code))
+	if err != nil {
+		return err
+	}
+
+	root = strings.Trim(filepath.ToSlash(root), "/")
+	for _, param := range params {
+		name := strings.TrimPrefix(param.ModuleName, "/")
+		if !strings.HasPrefix(name, root+"/") {
+			name = root + "/" + name
+		}
+		err = save(output, dir, name, []byte(param.FuncBody))
+		if err != nil {
+			log.Printf("Error saving file: %v\n", err)
+		}
+	}
+
+	log.Printf("Splitting \"%s\" done.", subContext)
+	return nil
+}
@@ -3,8 +3,6 @@ package unpack
 import (
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -13,11 +11,13 @@ import (
 	"github.com/Ackites/KillWxapkg/internal/enum"
 
 	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
 )
 
 // JavaScriptParser JavaScript 解析器
 type JavaScriptParser struct {
 	OutputDir string
+	Output    sink.Sink // 输出目标，为空时直接写入磁盘
 }
 
 // DefineParams 存储从 define 函数中提取的参数
@@ -207,7 +207,8 @@ func (p *JavaScriptParser) Parse(option config.WxapkgInfo) error {
 		dir = p.OutputDir
 	}
 
-	code, err := os.ReadFile(option.Option.ServiceSource)
+	output := outputOf(p.Output)
+	code, err := output.ReadFile(option.Option.ServiceSource)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
@@ -218,7 +219,7 @@ func (p *JavaScriptParser) Parse(option config.WxapkgInfo) error {
 	}
 
 	for _, param := range params {
-		err = save(filepath.Join(dir, param.ModuleName), []byte(param.FuncBody))
+		err = save(output, dir, param.ModuleName, []byte(param.FuncBody))
 		if err != nil {
 			log.Printf("Error saving file: %v\n", err)
 		}
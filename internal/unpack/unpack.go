@@ -2,128 +2,83 @@ package unpack
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/Ackites/KillWxapkg/internal/key"
 
 	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
 
 	formatter2 "github.com/Ackites/KillWxapkg/internal/formatter"
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
 )
 
-type WxapkgFile struct {
-	NameLen uint32
-	Name    string
-	Offset  uint32
-	Size    uint32
-}
-
-// UnpackWxapkg 解包 wxapkg 文件并将内容保存到指定目录
-func UnpackWxapkg(data []byte, outputDir string) ([]string, error) {
-	reader := bytes.NewReader(data)
-
-	// 读取文件头
-	var firstMark byte
-	if err := binary.Read(reader, binary.BigEndian, &firstMark); err != nil {
-		return nil, fmt.Errorf("读取首标记失败: %v", err)
-	}
-	if firstMark != 0xBE {
-		return nil, fmt.Errorf("无效的wxapkg文件: 首标记不正确")
-	}
-
-	var info1, indexInfoLength, bodyInfoLength uint32
-	if err := binary.Read(reader, binary.BigEndian, &info1); err != nil {
-		return nil, fmt.Errorf("读取info1失败: %v", err)
-	}
-	if err := binary.Read(reader, binary.BigEndian, &indexInfoLength); err != nil {
-		return nil, fmt.Errorf("读取索引段长度失败: %v", err)
-	}
-	if err := binary.Read(reader, binary.BigEndian, &bodyInfoLength); err != nil {
-		return nil, fmt.Errorf("读取数据段长度失败: %v", err)
-	}
-
-	// 验证长度的合理性
-	totalLength := uint64(indexInfoLength) + uint64(bodyInfoLength)
-	if totalLength > uint64(len(data)) {
-		return nil, fmt.Errorf("文件长度不足, 文件损坏: 索引段(%d) + 数据段(%d) > 文件总长度(%d)", indexInfoLength, bodyInfoLength, len(data))
-	}
-	totalLength = uint64(len(data))
-
-	var lastMark byte
-	if err := binary.Read(reader, binary.BigEndian, &lastMark); err != nil {
-		return nil, fmt.Errorf("读取尾标记失败: %v", err)
-	}
-	if lastMark != 0xED {
-		return nil, fmt.Errorf("无效的wxapkg文件: 尾标记不正确")
-	}
-
-	var fileCount uint32
-	if err := binary.Read(reader, binary.BigEndian, &fileCount); err != nil {
-		return nil, fmt.Errorf("读取文件数量失败: %v", err)
-	}
-
-	// 计算索引段的预期结束位置
-	expectedIndexEnd := uint64(reader.Size()) - uint64(bodyInfoLength)
-
-	// 读取索引
-	fileList := make([]WxapkgFile, fileCount)
-	var filelistNames []string
-	for i := range fileList {
-		if err := binary.Read(reader, binary.BigEndian, &fileList[i].NameLen); err != nil {
-			return nil, fmt.Errorf("读取文件名长度失败: %v", err)
-		}
-
-		if fileList[i].NameLen == 0 || fileList[i].NameLen > 1024 {
-			return nil, fmt.Errorf("文件名长度 %d 不合理", fileList[i].NameLen)
-		}
-
-		nameBytes := make([]byte, fileList[i].NameLen)
-		if _, err := io.ReadAtLeast(reader, nameBytes, int(fileList[i].NameLen)); err != nil {
-			return nil, fmt.Errorf("读取文件名失败: %v", err)
+// WxapkgFile wxapkg 索引项
+type WxapkgFile = wxapkg.WxapkgFile
+
+// UnpackWxapkg 解包 wxapkg 文件并将内容写入输出目标
+// r 为明文数据（可由 decrypt.NewReader 按需解密），size 为明文长度，文件内容通过 io.SectionReader 按需读取
+// 所有文件均写入输出根目录之内，重命名和跳过的文件记录在返回的报告中
+// options 可按通配符筛选需要解包的文件，报告中的文件列表始终包含全部文件，以便判断包类型
+// 宽松模式下索引和单个文件的错误不会中止解包，每个文件的恢复情况记录在报告中
+func UnpackWxapkg(r io.ReaderAt, size int64, output sink.Sink, options *Options) (*Report, error) {
+	report := &Report{}
+	salvage := options.salvage()
+	session := options.CurrentSession()
+
+	// 读取文件头和索引
+	var fileList []WxapkgFile
+	if salvage {
+		_, files, problems, err := wxapkg.SalvageIndex(r, size)
+		if err != nil {
+			return nil, err
 		}
-
-		fileList[i].Name = string(nameBytes)
-
-		filelistNames = append(filelistNames, fileList[i].Name)
-
-		if err := binary.Read(reader, binary.BigEndian, &fileList[i].Offset); err != nil {
-			return nil, fmt.Errorf("读取文件偏移量失败: %v", err)
+		for _, problem := range problems {
+			report.IndexErrors = append(report.IndexErrors, problem.Error())
 		}
-
-		if err := binary.Read(reader, binary.BigEndian, &fileList[i].Size); err != nil {
-			return nil, fmt.Errorf("读取文件大小失败: %v", err)
+		fileList = files
+	} else {
+		_, files, err := wxapkg.ReadIndex(r, size)
+		if err != nil {
+			return nil, err
 		}
+		fileList = files
+	}
 
-		// 验证文件偏移量和大小
-		fileEnd := uint64(fileList[i].Offset) + uint64(fileList[i].Size)
-		if fileEnd > totalLength {
-			return nil, fmt.Errorf("文件 %s 的结束位置 (%d) 超出了文件总长度 (%d)", fileList[i].Name, fileEnd, totalLength)
-		}
+	for _, file := range fileList {
+		report.Files = append(report.Files, file.Name)
+	}
 
-		// 验证我们是否仍在索引段内
-		currentPos := uint64(reader.Size()) - uint64(reader.Len())
-		if currentPos > expectedIndexEnd {
-			return nil, fmt.Errorf("索引读取超出预期范围: 当前位置 %d, 预期索引结束位置 %d", currentPos, expectedIndexEnd)
+	// 按通配符筛选文件
+	selected := make([]WxapkgFile, 0, len(fileList))
+	for _, file := range fileList {
+		if options.Selected(file.Name) {
+			selected = append(selected, file)
 		}
 	}
+	if len(selected) != len(fileList) {
+		log.Printf("已筛选 %d/%d 个文件进行解包\n", len(selected), len(fileList))
+	}
 
-	// 验证是否正确读完了整个索引段
-	currentPos := uint64(reader.Size()) - uint64(reader.Len())
-	if currentPos != expectedIndexEnd {
-		return nil, fmt.Errorf("索引段长度不符: 读取到位置 %d, 预期结束位置 %d", currentPos, expectedIndexEnd)
+	// 确定每个文件的安全路径
+	tasks := planExtraction(selected, report)
+
+	// 宽松模式下按实际数据长度截断或跳过文件
+	if salvage {
+		tasks = clampTasks(tasks, size, report)
 	}
 
 	// 控制并发数
 	const workerCount = 10
 	var wg sync.WaitGroup
-	fileChan := make(chan WxapkgFile, workerCount)
-	errChan := make(chan error, workerCount)
+	taskChan := make(chan int, workerCount)
+	results := make([]extractResult, len(tasks))
 
 	// 使用 sync.Pool 来复用缓冲区，减少内存分配和 GC 开销
 	var bufferPool = sync.Pool{
@@ -136,68 +91,98 @@ func UnpackWxapkg(data []byte, outputDir string) ([]string, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for file := range fileChan {
-				if err := processFile(outputDir, file, reader, &bufferPool); err != nil {
-					errChan <- fmt.Errorf("保存文件 %s 失败: %w", file.Name, err)
-				}
+			for index := range taskChan {
+				results[index] = processFile(output, tasks[index], r, &bufferPool, salvage, session.Config)
 			}
 		}()
 	}
 
-	for _, file := range fileList {
-		fileChan <- file
+	for index := range tasks {
+		taskChan <- index
 	}
-	close(fileChan)
+	close(taskChan)
 
 	// 等待所有 goroutine 完成
 	wg.Wait()
-	close(errChan)
 
 	// 检查是否有错误
-	if len(errChan) > 0 {
-		return nil, <-errChan
-	}
+	for index, result := range results {
+		task := tasks[index]
+		if !salvage {
+			if result.err != nil {
+				return nil, fmt.Errorf("保存文件 %s 失败: %w", task.file.Name, result.err)
+			}
+			continue
+		}
 
-	//const configJSON = `{
-	//    "description": "See https://developers.weixin.qq.com/miniprogram/dev/devtools/projectconfig.html",
-	//    "setting": {
-	//      "urlCheck": false
-	//    }
-	//  }`
+		recovery := Recovery{
+			Name:    task.file.Name,
+			Status:  RecoveryOK,
+			Offset:  task.file.Offset,
+			Size:    task.file.Size,
+			Written: result.written,
+		}
+		switch {
+		case result.err != nil:
+			recovery.Status = RecoveryFailed
+			recovery.Reason = result.err.Error()
+		case task.size < int64(task.file.Size):
+			recovery.Status = RecoveryTruncated
+			recovery.Reason = fmt.Sprintf("数据被截断，仅保存了 %d/%d 字节", task.size, task.file.Size)
+		}
+		if result.warning != "" {
+			recovery.Reason = strings.TrimPrefix(recovery.Reason+"; "+result.warning, "; ")
+		}
+		report.Recovery = append(report.Recovery, recovery)
+	}
 
-	// 保存 project.private.config.json
-	//configFile := filepath.Join(outputDir, "project.private.config.json")
-	//if err := os.WriteFile(configFile, []byte(configJSON), 0755); err != nil {
-	//	return nil, fmt.Errorf("保存文件 %s 失败: %w", configFile, err)
-	//}
+	// 按包内顺序排列恢复记录
+	sort.SliceStable(report.Recovery, func(i, j int) bool {
+		return report.Recovery[i].Offset < report.Recovery[j].Offset
+	})
 
-	return filelistNames, nil
+	return report, nil
 }
 
-// processFile 处理单个文件的读取、格式化和保存
-func processFile(outputDir string, file WxapkgFile, reader io.ReaderAt, bufferPool *sync.Pool) error {
-	fullPath := filepath.Join(outputDir, file.Name)
-	dir := filepath.Dir(fullPath)
+// extractResult 单个文件的解包结果
+type extractResult struct {
+	written int64
+	warning string
+	err     error
+}
 
-	// 创建目录
-	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
-		return fmt.Errorf("创建目录失败: %w", err)
+// clampTasks 将文件范围限制在实际数据长度内，数据完全缺失的文件记录为跳过
+func clampTasks(tasks []extractTask, size int64, report *Report) []extractTask {
+	clamped := make([]extractTask, 0, len(tasks))
+	for _, task := range tasks {
+		if int64(task.file.Offset) >= size && task.file.Size > 0 {
+			report.Recovery = append(report.Recovery, Recovery{
+				Name:   task.file.Name,
+				Status: RecoverySkipped,
+				Offset: task.file.Offset,
+				Size:   task.file.Size,
+				Reason: fmt.Sprintf("偏移量 %d 超出文件末尾 %d", task.file.Offset, size),
+			})
+			continue
+		}
+		task.size = min(task.size, max(size-int64(task.file.Offset), 0))
+		clamped = append(clamped, task)
 	}
+	return clamped
+}
 
-	// 创建文件
-	f, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+// processFile 处理单个文件的读取、格式化和保存
+// 宽松模式下格式化失败时保存原始内容
+func processFile(output sink.Sink, task extractTask, reader io.ReaderAt, bufferPool *sync.Pool, salvage bool, configManager *config.SharedConfigManager) (result extractResult) {
+	file := task.file
+	fullPath, _, err := SafeJoin(output.Root(), task.name)
 	if err != nil {
-		return fmt.Errorf("创建文件失败: %w", err)
+		result.err = err
+		return
 	}
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			log.Printf("关闭文件 %s 失败: %v\n", file.Name, err)
-		}
-	}(f)
 
 	// 使用 io.NewSectionReader 创建一个只读取指定部分的 Reader
-	sectionReader := io.NewSectionReader(reader, int64(file.Offset), int64(file.Size))
+	sectionReader := io.NewSectionReader(reader, int64(file.Offset), task.size)
 
 	// 从 bufferPool 获取缓冲区
 	buf := bufferPool.Get().(*bytes.Buffer)
@@ -205,37 +190,47 @@ func processFile(outputDir string, file WxapkgFile, reader io.ReaderAt, bufferPo
 	buf.Reset()
 
 	// 读取文件内容
+	complete := true
 	if _, err := io.Copy(buf, sectionReader); err != nil {
-		return fmt.Errorf("读取文件内容失败: %w", err)
+		if !salvage || buf.Len() == 0 {
+			result.err = fmt.Errorf("读取文件内容失败: %w", err)
+			return
+		}
+		complete = false
+		result.warning = fmt.Sprintf("读取中断，已保存 %d 字节: %v", buf.Len(), err)
 	}
 	content := buf.Bytes()
 
-	// 获取文件格式化器
-	ext := filepath.Ext(file.Name)
-	formatter, err := formatter2.GetFormatter(ext)
-	if err == nil {
-		content, err = formatter.Format(content)
-		if err != nil {
-			return fmt.Errorf("格式化文件失败: %w", err)
+	// 获取文件格式化器，不完整的文件不做格式化
+	ext := filepath.Ext(task.name)
+	formatter, err := formatter2.GetFormatter(configManager, ext)
+	if err == nil && complete && task.size == int64(file.Size) {
+		formatted, err := formatter.Format(content)
+		switch {
+		case err == nil:
+			content = formatted
+		case salvage:
+			result.warning = fmt.Sprintf("格式化失败，已保存原始内容: %v", err)
+		default:
+			result.err = fmt.Errorf("格式化文件失败: %w", err)
+			return
 		}
 	}
 
 	// 写入文件内容
-	if _, err := f.Write(content); err != nil {
-		return fmt.Errorf("写入文件失败: %w", err)
+	if err := output.WriteFile(fullPath, content); err != nil {
+		result.err = fmt.Errorf("写入文件失败: %w", err)
+		return
 	}
+	result.written = int64(len(content))
 
-	configManager := config.NewSharedConfigManager()
-	if sensitive, ok := configManager.Get("sensitive"); ok {
-		if p, o := sensitive.(bool); o {
-			if p {
-				// 查找敏感信息
-				if err := key.MatchRules(string(content)); err != nil {
-					return fmt.Errorf("%v", err)
-				}
-			}
+	if configManager.GetBool("sensitive") {
+		// 查找敏感信息
+		if err := key.MatchRules(string(content)); err != nil {
+			result.err = fmt.Errorf("%v", err)
+			return
 		}
 	}
 
-	return nil
+	return
 }
@@ -0,0 +1,219 @@
+package unpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/Ackites/KillWxapkg/internal/enum"
+
+	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
+)
+
+// 调试插件的小程序中引用插件时使用的名称
+const hostPluginName = "myPlugin"
+
+// PluginParser 小程序插件解析器，还原 plugin.json 及组件配置，并生成调试插件用的小程序
+type PluginParser struct {
+	OutputDir string    // 插件工程根目录，插件代码位于其下的 plugin 目录
+	Output    sink.Sink // 输出目标，为空时直接写入磁盘
+}
+
+// PluginConfig 存储插件配置
+type PluginConfig struct {
+	PublicComponents map[string]string `json:"publicComponents,omitempty"`
+	Pages            map[string]string `json:"pages,omitempty"`
+	Main             string            `json:"main,omitempty"`
+}
+
+// Parse 还原插件配置
+func (p *PluginParser) Parse(option config.WxapkgInfo) error {
+	output := outputOf(p.Output)
+	dir := option.SourcePath
+
+	var e struct {
+		PublicComponents map[string]interface{} `json:"publicComponents"`
+		Pages            map[string]interface{} `json:"pages"`
+		Main             string                 `json:"main"`
+	}
+	content, err := output.ReadFile(filepath.Join(dir, enum.PluginJson))
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(content, &e)
+	if err != nil {
+		return err
+	}
+
+	plugin := PluginConfig{
+		PublicComponents: pluginPaths(e.PublicComponents),
+		Pages:            pluginPaths(e.Pages),
+		Main:             strings.TrimPrefix(e.Main, "/"),
+	}
+
+	service, err := output.ReadFile(option.Option.ServiceSource)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// 未声明 main 时使用 define 的 index.js 作为导出模块
+	modules := defineNames(string(service))
+	if plugin.Main == "" && modules["index.js"] {
+		plugin.Main = "index.js"
+	}
+	if plugin.Main != "" && !modules[plugin.Main] {
+		log.Printf("插件导出模块 %s 未在 %s 中找到\n", plugin.Main, option.Option.ServiceSource)
+	}
+
+	// 组件配置，公开组件缺少配置时补全
+	configs, err := wxAppCodeConfigs(string(service))
+	if err != nil {
+		log.Printf("Error reading component config: %v\n", err)
+	}
+	for _, path := range plugin.PublicComponents {
+		if _, ok := configs[path+".json"]; !ok {
+			configs[path+".json"] = map[string]interface{}{"component": true, "usingComponents": map[string]interface{}{}}
+		}
+	}
+	for name, window := range configs {
+		windowContent, _ := json.MarshalIndent(window, "", "    ")
+		err = save(output, dir, name, windowContent)
+		if err != nil {
+			log.Printf("Error saving file %s: %v\n", name, err)
+		}
+	}
+
+	pluginContent, _ := json.MarshalIndent(plugin, "", "    ")
+	err = save(output, dir, enum.PluginJson, pluginContent)
+	if err != nil {
+		return err
+	}
+
+	p.saveHost(output, option.WxAppId, plugin)
+
+	log.Printf("Plugin config processed: %s\n", filepath.Join(dir, enum.PluginJson))
+	return nil
+}
+
+// pluginPaths 将 plugin.json 中的路径转换为不带扩展名的相对路径，路径可能为字符串或带 path 的对象
+func pluginPaths(paths map[string]interface{}) map[string]string {
+	if len(paths) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(paths))
+	for name, value := range paths {
+		var path string
+		switch v := value.(type) {
+		case string:
+			path = v
+		case map[string]interface{}:
+			path, _ = v["path"].(string)
+		}
+		if path == "" {
+			continue
+		}
+		result[name] = changeExt(strings.TrimPrefix(path, "/"), "")
+	}
+	return result
+}
+
+// defineNames 返回代码中 define 的所有模块名
+func defineNames(code string) map[string]bool {
+	names := make(map[string]bool)
+	re := regexp.MustCompile(`define\s*\(\s*["']([^"']+)["']`)
+	for _, match := range re.FindAllStringSubmatch(code, -1) {
+		names[strings.TrimPrefix(match[1], "/")] = true
+	}
+	return names
+}
+
+// wxAppCodeConfigs 读取代码中 __wxAppCode__ 保存的页面及组件配置，键为 json 文件的相对路径
+func wxAppCodeConfigs(code string) (map[string]interface{}, error) {
+	configs := make(map[string]interface{})
+	matches := findMatches(`__wxAppCode__\['[^']+\.json'\]\s*=\s*({[^;]*});`, code)
+	if len(matches) == 0 {
+		return configs, nil
+	}
+
+	attachInfo := make(map[string]interface{})
+	vm := goja.New()
+	err := vm.Set("__wxAppCode__", attachInfo)
+	if err != nil {
+		return configs, err
+	}
+	_, err = vm.RunString(strings.Join(matches, ""))
+	if err != nil {
+		return configs, err
+	}
+	for name, info := range attachInfo {
+		configs[strings.TrimPrefix(name, "/")] = info
+	}
+	return configs, nil
+}
+
+// saveHost 生成调试插件用的小程序，引用插件的所有公开组件和页面，已存在的文件不覆盖
+func (p *PluginParser) saveHost(output sink.Sink, appID string, plugin PluginConfig) {
+	dir := filepath.Join(p.OutputDir, enum.MiniprogramRoot)
+
+	provider := appID
+	if provider == "" {
+		provider = "wx0000000000000000"
+	}
+	appContent, _ := json.MarshalIndent(map[string]interface{}{
+		"pages": []string{"pages/index/index"},
+		"plugins": map[string]interface{}{
+			hostPluginName: map[string]string{"version": "dev", "provider": provider},
+		},
+	}, "", "    ")
+
+	components := make(map[string]string)
+	var names []string
+	for name := range plugin.PublicComponents {
+		components[name] = "plugin://" + hostPluginName + "/" + name
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pageContent, _ := json.MarshalIndent(map[string]interface{}{"usingComponents": components}, "", "    ")
+
+	var wxml strings.Builder
+	for _, name := range names {
+		wxml.WriteString(fmt.Sprintf("<%s />\n", name))
+	}
+	var pages []string
+	for name := range plugin.Pages {
+		pages = append(pages, name)
+	}
+	sort.Strings(pages)
+	for _, name := range pages {
+		wxml.WriteString(fmt.Sprintf("<navigator url=\"plugin://%s/%s\">%s</navigator>\n", hostPluginName, name, name))
+	}
+
+	script := "Page({})\n"
+	if plugin.Main != "" {
+		script = "const plugin = requirePlugin('" + hostPluginName + "')\n\nPage({\n    onLoad() {\n        console.log(plugin)\n    }\n})\n"
+	}
+
+	files := map[string][]byte{
+		"app.js":                 []byte("App({})\n"),
+		"app.json":               appContent,
+		"pages/index/index.js":   []byte(script),
+		"pages/index/index.json": pageContent,
+		"pages/index/index.wxml": []byte(wxml.String()),
+		"pages/index/index.wxss": []byte(""),
+	}
+	for name, content := range files {
+		if output.Exists(filepath.Join(dir, name)) {
+			continue
+		}
+		if err := save(output, dir, name, content); err != nil {
+			log.Printf("Error saving file: %v\n", err)
+		}
+	}
+}
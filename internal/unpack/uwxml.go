@@ -0,0 +1,687 @@
+package unpack
+
+import (
+	"log"
+	"path"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/token"
+)
+
+// wxmlNode 反编译得到的 WXML 节点
+type wxmlNode struct {
+	Tag      string // 标签名，为空时为文本节点
+	Text     string // 文本节点的内容
+	Attrs    []wxmlAttr
+	Children []*wxmlNode
+	virtual  bool // 编译产生的虚拟节点，输出时只保留子节点
+}
+
+// wxmlAttr 节点属性，按源代码中的顺序保存
+type wxmlAttr struct {
+	Name  string
+	Value string
+	Bare  bool // 没有值的属性，如 <input disabled>
+}
+
+// 控制语句的属性名
+const (
+	wxIf       = "wx:if"
+	wxElif     = "wx:elif"
+	wxElse     = "wx:else"
+	wxFor      = "wx:for"
+	wxForItem  = "wx:for-item"
+	wxForIndex = "wx:for-index"
+	wxKey      = "wx:key"
+)
+
+// attr 返回属性值及是否存在
+func (n *wxmlNode) attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// hasAttr 是否存在任一属性
+func (n *wxmlNode) hasAttr(names ...string) bool {
+	for _, name := range names {
+		if _, ok := n.attr(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// wxmlDecompiler 从编译后的视图代码中还原 WXML 源代码
+// 表达式保存在 gz$gwx 函数生成的表达式表中，页面由 e_ 中注册的生成函数逐个创建节点
+type wxmlDecompiler struct {
+	src   *jsSource
+	files map[string]*wxmlNode // .wxml 文件相对于包根目录的路径 -> 根节点
+}
+
+// wxmlScope 解释生成函数时的变量
+type wxmlScope struct {
+	paths     []string                        // 文件列表 x
+	file      string                          // 正在还原的文件
+	imports   []*wxmlNode                     // 正在还原的文件引用的模板文件
+	templates map[string]string               // 模板变量 -> 模板名
+	tables    map[string][]interface{}        // gz$gwx 函数名 -> 表达式表
+	table     []interface{}                   // 当前使用的表达式表
+	nodes     map[string]*wxmlNode            // 节点变量
+	texts     map[string]string               // 表达式变量
+	funcs     map[string]*ast.FunctionLiteral // 函数变量
+}
+
+// decompileWxml 反编译视图代码中所有的 WXML 文件，无法反编译的文件不在结果中
+func decompileWxml(src *jsSource) map[string]*wxmlNode {
+	d := &wxmlDecompiler{src: src, files: make(map[string]*wxmlNode)}
+	src.eachBody(d.decompileBody)
+	for _, root := range d.files {
+		simplify(root)
+	}
+	return d.files
+}
+
+// decompileBody 反编译声明了文件列表 x 的函数体
+func (d *wxmlDecompiler) decompileBody(list []ast.Statement) {
+	paths := wxmlPaths(list)
+	if len(paths) == 0 {
+		return
+	}
+
+	scope := &wxmlScope{
+		paths:     paths,
+		tables:    d.opsTables(list),
+		nodes:     make(map[string]*wxmlNode),
+		texts:     make(map[string]string),
+		funcs:     make(map[string]*ast.FunctionLiteral),
+		templates: make(map[string]string),
+	}
+
+	for _, statement := range list {
+		if variables, ok := statement.(*ast.VariableStatement); ok {
+			for _, binding := range variables.List {
+				if function, ok := binding.Initializer.(*ast.FunctionLiteral); ok {
+					scope.funcs[identName(binding.Target)] = function
+				}
+			}
+		}
+	}
+
+	// 其他函数体中已还原的文件不再重复还原
+	roots := make(map[string]*wxmlNode)
+	root := func(index int) *wxmlNode {
+		if index < 0 || index >= len(paths) {
+			return nil
+		}
+		name := normalizePath(paths[index])
+		if node, ok := roots[name]; ok {
+			return node
+		}
+		if d.files[name] != nil {
+			return nil
+		}
+		node := &wxmlNode{Tag: "root"}
+		roots[name] = node
+		return node
+	}
+
+	for _, statement := range list {
+		// 模板的定义，d_[x[0]]["name"]=function(e,s,r,gg){...}
+		if index, name, function, ok := templateAssignment(statement); ok {
+			parent := root(index)
+			if parent == nil {
+				continue
+			}
+			template := &wxmlNode{Tag: "template", Attrs: []wxmlAttr{{Name: "name", Value: name}}}
+			parent.Children = append(parent.Children, template)
+			scope.table = scope.tables["z"]
+			scope.file = paths[index]
+			scope.run(function, template)
+			continue
+		}
+
+		// 生成函数的注册，e_[x[0]]={f:m0,j:[],i:[],ti:[],ic:[]}
+		index, object, ok := registryAssignment(statement, "e_")
+		if !ok {
+			continue
+		}
+		literal, ok := object.(*ast.ObjectLiteral)
+		if !ok {
+			continue
+		}
+		function := scope.funcs[identName(propertyValue(literal, "f"))]
+		parent := root(index)
+		if function == nil || parent == nil {
+			continue
+		}
+		scope.table = scope.tables["z"]
+		scope.file = paths[index]
+		scope.imports = nil
+		scope.run(function, parent)
+
+		// 引用的模板文件放在文件开头，ti 中未通过 _ai 引用的文件同样补全
+		if imports, ok := propertyValue(literal, "ti").(*ast.ArrayLiteral); ok {
+			for _, item := range imports.Value {
+				if i, ok := pathIndex(item); ok {
+					scope.addImport(i)
+				}
+			}
+		}
+		parent.Children = append(scope.imports, parent.Children...)
+	}
+
+	for name, node := range roots {
+		d.files[name] = node
+	}
+}
+
+// templateAssignment 解析 d_[x[i]]["name"]=function(){...} 形式的模板定义，返回文件序号、模板名及模板的生成函数
+func templateAssignment(statement ast.Statement) (int, string, *ast.FunctionLiteral, bool) {
+	expression, ok := statement.(*ast.ExpressionStatement)
+	if !ok {
+		return 0, "", nil, false
+	}
+	assign, ok := expression.Expression.(*ast.AssignExpression)
+	if !ok || assign.Operator != token.ASSIGN {
+		return 0, "", nil, false
+	}
+	function, ok := assign.Right.(*ast.FunctionLiteral)
+	if !ok {
+		return 0, "", nil, false
+	}
+	outer, ok := assign.Left.(*ast.BracketExpression)
+	if !ok {
+		return 0, "", nil, false
+	}
+	inner, ok := outer.Left.(*ast.BracketExpression)
+	if !ok || identName(inner.Left) != "d_" {
+		return 0, "", nil, false
+	}
+	index, okIndex := pathIndex(inner.Member)
+	name, okName := stringValue(outer.Member)
+	return index, name, function, okIndex && okName
+}
+
+// wxmlPaths 读取 var x=['./a.wxml', ...] 声明的文件列表
+func wxmlPaths(list []ast.Statement) []string {
+	for _, statement := range list {
+		variables, ok := statement.(*ast.VariableStatement)
+		if !ok {
+			continue
+		}
+		for _, binding := range variables.List {
+			array, ok := binding.Initializer.(*ast.ArrayLiteral)
+			if identName(binding.Target) != "x" || !ok {
+				continue
+			}
+			var paths []string
+			for _, item := range array.Value {
+				path, ok := stringValue(item)
+				if !ok {
+					return nil
+				}
+				paths = append(paths, path)
+			}
+			return paths
+		}
+	}
+	return nil
+}
+
+// opsTables 运行函数体中生成表达式表的代码，返回 gz$gwx 函数名及旧版编译器的全局变量 z 对应的表达式表
+func (d *wxmlDecompiler) opsTables(list []ast.Statement) map[string][]interface{} {
+	tables := make(map[string][]interface{})
+
+	var sb strings.Builder
+	var names []string
+	sb.WriteString("var __WXML_GLOBAL__={ops_set:{},ops_cached:{},ops_init:{}};\n")
+	for _, statement := range list {
+		switch st := statement.(type) {
+		case *ast.FunctionDeclaration:
+			if name := identName(st.Function.Name); strings.HasPrefix(name, "gz$") {
+				names = append(names, name)
+				sb.WriteString(d.src.text(st) + "\n")
+			}
+		case *ast.VariableStatement:
+			if len(st.List) == 1 && identName(st.List[0].Target) == "z" {
+				sb.WriteString(d.src.text(st) + ";\n")
+			}
+		case *ast.ExpressionStatement:
+			// 旧版编译器的 (function(z){...Z([3,'a'])...})(z)
+			call, ok := st.Expression.(*ast.CallExpression)
+			if !ok || len(call.ArgumentList) != 1 || identName(call.ArgumentList[0]) != "z" {
+				continue
+			}
+			if function, ok := call.Callee.(*ast.FunctionLiteral); ok {
+				sb.WriteString("try{(" + d.src.text(function) + ")(z)}catch(e){};\n")
+			}
+		}
+	}
+
+	vm := goja.New()
+	if _, err := vm.RunString(sb.String()); err != nil {
+		log.Printf("Error evaluating WXML expressions: %v\n", err)
+		return tables
+	}
+	if z := vm.Get("z"); z != nil {
+		if table, ok := z.Export().([]interface{}); ok {
+			tables["z"] = table
+		}
+	}
+	for _, name := range names {
+		value := vm.Get(name)
+		if value == nil {
+			continue
+		}
+		fn, ok := goja.AssertFunction(value)
+		if !ok {
+			continue
+		}
+		result, err := fn(goja.Undefined())
+		if err != nil {
+			log.Printf("Error evaluating %s: %v\n", name, err)
+			continue
+		}
+		if table, ok := result.Export().([]interface{}); ok {
+			tables[name] = table
+		}
+	}
+	return tables
+}
+
+// registryAssignment 解析 name[x[i]]=value 形式的赋值语句，返回文件序号及赋值的表达式
+func registryAssignment(statement ast.Statement, name string) (int, ast.Expression, bool) {
+	expression, ok := statement.(*ast.ExpressionStatement)
+	if !ok {
+		return 0, nil, false
+	}
+	assign, ok := expression.Expression.(*ast.AssignExpression)
+	if !ok || assign.Operator != token.ASSIGN {
+		return 0, nil, false
+	}
+	left, ok := assign.Left.(*ast.BracketExpression)
+	if !ok || identName(left.Left) != name {
+		return 0, nil, false
+	}
+	index, ok := pathIndex(left.Member)
+	return index, assign.Right, ok
+}
+
+// pathIndex 解析 x[i] 形式的文件引用
+func pathIndex(expression ast.Expression) (int, bool) {
+	bracket, ok := expression.(*ast.BracketExpression)
+	if !ok || identName(bracket.Left) != "x" {
+		return 0, false
+	}
+	return intValue(bracket.Member)
+}
+
+// intValue 读取整数字面量，支持负数
+func intValue(expression ast.Expression) (int, bool) {
+	switch ex := expression.(type) {
+	case *ast.NumberLiteral:
+		return opsNumber(ex.Value)
+	case *ast.UnaryExpression:
+		if ex.Operator == token.MINUS {
+			value, ok := intValue(ex.Operand)
+			return -value, ok
+		}
+	}
+	return 0, false
+}
+
+// propertyValue 返回对象字面量中指定键的值
+func propertyValue(object *ast.ObjectLiteral, key string) ast.Expression {
+	for _, property := range object.Value {
+		keyed, ok := property.(*ast.PropertyKeyed)
+		if !ok {
+			continue
+		}
+		name, ok := stringValue(keyed.Key)
+		if !ok {
+			name = identName(keyed.Key)
+		}
+		if name == key {
+			return keyed.Value
+		}
+	}
+	return nil
+}
+
+// runtimeCall 解析运行时函数的调用，_oz(z,1,...) 等新版函数去掉第一个参数 z 后与旧版的 _o(1,...) 参数一致
+func runtimeCall(expression ast.Expression) (string, []ast.Expression) {
+	name, call := callOf(expression)
+	if call == nil {
+		return "", nil
+	}
+	switch name {
+	case "_oz", "_1z", "_rz", "_mz", "_2z":
+		if len(call.ArgumentList) == 0 {
+			return "", nil
+		}
+		return strings.TrimSuffix(name, "z"), call.ArgumentList[1:]
+	}
+	return name, call.ArgumentList
+}
+
+// expr 返回表达式表中第 i 项还原后的值
+func (s *wxmlScope) expr(expression ast.Expression) string {
+	i, ok := intValue(expression)
+	if !ok || i < 0 || i >= len(s.table) {
+		return ""
+	}
+	return restoreOps(s.table[i])
+}
+
+// run 解释生成函数，第三个参数为节点挂载的父节点
+func (s *wxmlScope) run(function *ast.FunctionLiteral, parent *wxmlNode) {
+	if function.ParameterList == nil || len(function.ParameterList.List) < 3 || function.Body == nil {
+		return
+	}
+	s.nodes[identName(function.ParameterList.List[2].Target)] = parent
+	s.statements(function.Body.List)
+}
+
+// statements 依次解释语句
+func (s *wxmlScope) statements(list []ast.Statement) {
+	for _, statement := range list {
+		s.statement(statement)
+	}
+}
+
+// statement 解释生成函数中的语句，无法识别的语句忽略
+func (s *wxmlScope) statement(statement ast.Statement) {
+	switch st := statement.(type) {
+	case *ast.VariableStatement:
+		for _, binding := range st.List {
+			s.declare(identName(binding.Target), binding.Initializer)
+		}
+	case *ast.ExpressionStatement:
+		s.call(st.Expression)
+	case *ast.IfStatement:
+		s.condition(st)
+	case *ast.BlockStatement:
+		s.statements(st.List)
+	case *ast.TryStatement:
+		s.statements(st.Body.List)
+	}
+}
+
+// declare 解释变量声明
+func (s *wxmlScope) declare(name string, initializer ast.Expression) {
+	// 各生成函数中的变量名会重复，声明时丢弃之前的同名变量
+	delete(s.nodes, name)
+	delete(s.texts, name)
+	delete(s.templates, name)
+
+	if function, ok := initializer.(*ast.FunctionLiteral); ok {
+		s.funcs[name] = function
+		return
+	}
+	if binary, ok := isLogicalOr(initializer); ok {
+		initializer = binary.Left
+	}
+
+	callee, args := runtimeCall(initializer)
+	switch {
+	case callee == "_n" && len(args) > 0:
+		tag, _ := stringValue(args[0])
+		s.nodes[name] = &wxmlNode{Tag: tag}
+	case callee == "_m" && len(args) > 1:
+		tag, _ := stringValue(args[0])
+		node := &wxmlNode{Tag: tag}
+		s.attrs(node, args[1])
+		s.nodes[name] = node
+	case callee == "_v":
+		s.nodes[name] = &wxmlNode{Tag: "block", virtual: true}
+	case (callee == "_o" || callee == "_1") && len(args) > 0:
+		s.texts[name] = s.expr(args[0])
+	case callee == "_gd" && len(args) > 1:
+		// 模板 var t=_gd(x[0],name,e_,d_)，名称为表达式变量
+		s.templates[name] = s.texts[identName(args[1])]
+	case strings.HasPrefix(callee, "gz$"):
+		s.table = s.tables[callee]
+	}
+}
+
+// attrs 解析 _m 的属性列表，第一个属性的序号为绝对位置，之后的序号相对于第一个
+func (s *wxmlScope) attrs(node *wxmlNode, expression ast.Expression) {
+	array, ok := expression.(*ast.ArrayLiteral)
+	if !ok {
+		return
+	}
+	base := 0
+	for i := 0; i+1 < len(array.Value); i += 2 {
+		name, _ := stringValue(array.Value[i])
+		offset, ok := intValue(array.Value[i+1])
+		if !ok {
+			continue
+		}
+		if base+offset < 0 {
+			node.Attrs = append(node.Attrs, wxmlAttr{Name: name, Bare: true})
+			continue
+		}
+		index := base + offset
+		if index < len(s.table) {
+			node.Attrs = append(node.Attrs, wxmlAttr{Name: name, Value: restoreOps(s.table[index])})
+		}
+		// 与运行时 _m 一致，基准位置为 0 时以当前序号作为基准
+		if base == 0 {
+			base = offset
+		}
+	}
+}
+
+// call 解释运行时函数的调用
+func (s *wxmlScope) call(expression ast.Expression) {
+	callee, args := runtimeCall(expression)
+	switch {
+	case callee == "_" && len(args) > 1:
+		parent := s.nodes[identName(args[0])]
+		if parent == nil {
+			return
+		}
+		if child := s.child(args[1]); child != nil {
+			parent.Children = append(parent.Children, child)
+		}
+	case callee == "_r" && len(args) > 2:
+		node := s.nodes[identName(args[0])]
+		name, _ := stringValue(args[1])
+		if node != nil {
+			node.Attrs = append(node.Attrs, wxmlAttr{Name: name, Value: s.expr(args[2])})
+		}
+	case callee == "_2" && len(args) > 8:
+		s.loop(args)
+	case callee == "_ai" && len(args) > 1:
+		// 引用模板文件 _ai(i,x[1],e_,x[0],1,1)
+		if index, ok := pathIndex(args[1]); ok {
+			s.addImport(index)
+		}
+	case callee == "_ic" && len(args) > 5:
+		// 包含文件 _ic(x[1],e_,x[0],e,s,node,gg)
+		parent := s.nodes[identName(args[5])]
+		if index, ok := pathIndex(args[0]); ok && parent != nil && index >= 0 && index < len(s.paths) {
+			parent.Children = append(parent.Children, &wxmlNode{Tag: "include", Attrs: []wxmlAttr{{Name: "src", Value: s.relative(index)}}})
+		}
+	case len(args) > 2:
+		// 使用模板 t(data,data,node,gg)，数据为 _1 的表达式变量
+		template, ok := s.templates[callee]
+		parent := s.nodes[identName(args[2])]
+		if !ok || parent == nil {
+			return
+		}
+		node := &wxmlNode{Tag: "template", Attrs: []wxmlAttr{{Name: "is", Value: template}}}
+		if data := s.texts[identName(args[0])]; data != "" {
+			node.Attrs = append(node.Attrs, wxmlAttr{Name: "data", Value: data})
+		}
+		parent.Children = append(parent.Children, node)
+	}
+}
+
+// addImport 在正在还原的文件中引用模板文件，重复的引用忽略
+func (s *wxmlScope) addImport(index int) {
+	if index < 0 || index >= len(s.paths) {
+		return
+	}
+	src := s.relative(index)
+	for _, node := range s.imports {
+		if value, _ := node.attr("src"); value == src {
+			return
+		}
+	}
+	s.imports = append(s.imports, &wxmlNode{Tag: "import", Attrs: []wxmlAttr{{Name: "src", Value: src}}})
+}
+
+// relative 返回从正在还原的文件到文件列表中第 index 个文件的相对路径
+func (s *wxmlScope) relative(index int) string {
+	return relativePath(path.Dir(normalizePath(s.file)), normalizePath(s.paths[index]))
+}
+
+// child 返回追加到父节点的节点或文本
+func (s *wxmlScope) child(expression ast.Expression) *wxmlNode {
+	if name := identName(expression); name != "" {
+		if node, ok := s.nodes[name]; ok {
+			return node
+		}
+		if text, ok := s.texts[name]; ok {
+			return &wxmlNode{Text: text}
+		}
+		return nil
+	}
+	if callee, args := runtimeCall(expression); callee == "_o" && len(args) > 0 {
+		return &wxmlNode{Text: s.expr(args[0])}
+	}
+	return nil
+}
+
+// loop 解释 _2(1,func,e,s,gg,node,'item','index','key') 生成的 wx:for 列表
+func (s *wxmlScope) loop(args []ast.Expression) {
+	parent := s.nodes[identName(args[5])]
+	function := s.funcs[identName(args[1])]
+	if parent == nil || function == nil {
+		return
+	}
+	item, _ := stringValue(args[6])
+	index, _ := stringValue(args[7])
+	key, _ := stringValue(args[8])
+
+	block := &wxmlNode{Tag: "block", Attrs: []wxmlAttr{{Name: wxFor, Value: s.expr(args[0])}}}
+	if item != "" && item != "item" {
+		block.Attrs = append(block.Attrs, wxmlAttr{Name: wxForItem, Value: item})
+	}
+	if index != "" && index != "index" {
+		block.Attrs = append(block.Attrs, wxmlAttr{Name: wxForIndex, Value: index})
+	}
+	if key != "" {
+		block.Attrs = append(block.Attrs, wxmlAttr{Name: wxKey, Value: key})
+	}
+	parent.Children = append(parent.Children, block)
+	s.run(function, block)
+}
+
+// condition 解释 if(_o(1,e,s,gg)){node.wxVkey=1 ...}else{...} 生成的条件渲染
+// 每个分支以 wxVkey 标记所属的虚拟节点，分支中追加到该节点的子节点属于该条件
+func (s *wxmlScope) condition(statement *ast.IfStatement) {
+	var branch ast.Statement = statement
+	for i := 0; branch != nil; i++ {
+		var test ast.Expression
+		var body ast.Statement
+		if st, ok := branch.(*ast.IfStatement); ok {
+			test, body, branch = st.Test, st.Consequent, st.Alternate
+		} else {
+			body, branch = branch, nil
+		}
+
+		attr := wxmlAttr{Name: wxElse, Bare: true}
+		if test != nil {
+			callee, args := runtimeCall(test)
+			if callee != "_o" || len(args) == 0 {
+				// 不是条件渲染，如模板是否存在的判断
+				s.statement(body)
+				return
+			}
+			attr = wxmlAttr{Name: wxIf, Value: s.expr(args[0])}
+			if i > 0 {
+				attr.Name = wxElif
+			}
+		}
+
+		list := []ast.Statement{body}
+		if block, ok := body.(*ast.BlockStatement); ok {
+			list = block.List
+		}
+		name := branchNode(list)
+		parent := s.nodes[name]
+		if parent == nil {
+			s.statements(list)
+			continue
+		}
+		block := &wxmlNode{Tag: "block", Attrs: []wxmlAttr{attr}}
+		parent.Children = append(parent.Children, block)
+		s.nodes[name] = block
+		s.statements(list)
+		s.nodes[name] = parent
+	}
+}
+
+// branchNode 返回分支中 node.wxVkey=1 标记的节点变量名
+func branchNode(list []ast.Statement) string {
+	for _, statement := range list {
+		expression, ok := statement.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+		assign, ok := expression.Expression.(*ast.AssignExpression)
+		if !ok {
+			continue
+		}
+		if dot, ok := assign.Left.(*ast.DotExpression); ok && dot.Identifier.Name == "wxVkey" {
+			return identName(dot.Left)
+		}
+	}
+	return ""
+}
+
+// simplify 展开虚拟节点，只包含一个元素的条件或列表将控制属性移到该元素上
+func simplify(node *wxmlNode) {
+	var children []*wxmlNode
+	for _, child := range node.Children {
+		simplify(child)
+		if child.Tag == "" && child.Text == "" {
+			continue
+		}
+		if child.virtual && len(child.Attrs) == 0 {
+			children = append(children, child.Children...)
+			continue
+		}
+		children = append(children, child)
+	}
+	node.Children = children
+
+	if node.Tag != "block" || len(node.Children) != 1 {
+		return
+	}
+	only := node.Children[0]
+	if only.Tag == "" || only.virtual {
+		return
+	}
+	switch {
+	case node.hasAttr(wxFor):
+		if only.hasAttr(wxFor) {
+			return
+		}
+	case node.hasAttr(wxIf, wxElif, wxElse):
+		if only.hasAttr(wxFor, wxIf, wxElif, wxElse) {
+			return
+		}
+	default:
+		return
+	}
+	*node = wxmlNode{Tag: only.Tag, Attrs: append(node.Attrs, only.Attrs...), Children: only.Children}
+}
@@ -0,0 +1,238 @@
+package unpack
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+)
+
+// wccRuntime 编译产物中 _m 及其依赖的运行时函数，_r 只记录属性对应的表达式序号
+const wccRuntime = `
+function _n(tag){return {tag:tag,attr:{}}}
+function _r(node,attrname,opindex,env,scope,global){node.attr[attrname]=opindex}
+function _m(tag,attrs,generics,env,scope,global){var tmp=_n(tag);var base=0;for(var i=0;i<attrs.length;i+=2){if(base+attrs[i+1]<0){tmp.attr[attrs[i]]=true}else{_r(tmp,attrs[i],base+attrs[i+1],env,scope,global);if(base===0)base=attrs[i+1]}}return tmp}
+`
+
+func TestAttrsMatchesRuntime(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`['class',0,'id',1,'style',2]`,
+		`['class',4,'id',1,'style',2]`,
+		`['a',0,'b',0,'c',3,'d',1]`,
+		`['disabled',-1,'class',2,'id',1]`,
+		`['class',5,'hidden',-6,'id',1]`,
+		`['class',3,'hidden',-4,'checked',-9,'id',2]`,
+	}
+
+	vm := goja.New()
+	if _, err := vm.RunString(wccRuntime); err != nil {
+		t.Fatal(err)
+	}
+	table, err := vm.RunString(`(function(){var z=[];for(var i=0;i<16;i++)z.push([3,'v'+i]);return z})()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := &wxmlScope{table: table.Export().([]interface{})}
+
+	for _, attrs := range tests {
+		t.Run(attrs, func(t *testing.T) {
+			code := "_m('view'," + attrs + ",[],e,s,gg)"
+			value, err := vm.RunString("(function(){var e,s,gg;return " + code + ".attr})()")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make(map[string]string)
+			for name, index := range value.Export().(map[string]interface{}) {
+				if index == true {
+					want[name] = "bare"
+				} else {
+					want[name] = fmt.Sprintf("v%v", index)
+				}
+			}
+
+			src, err := parseJs(code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			call := src.program.Body[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+			node := &wxmlNode{}
+			scope.attrs(node, call.ArgumentList[1])
+			got := make(map[string]string)
+			for _, attr := range node.Attrs {
+				if attr.Bare {
+					got[attr.Name] = "bare"
+				} else {
+					got[attr.Name] = attr.Value
+				}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("attrs(%s) = %v, runtime = %v", attrs, got, want)
+			}
+		})
+	}
+}
+
+func TestAttrsOrder(t *testing.T) {
+	src, err := parseJs(`_m('view',['id',2,'disabled',-3,'class',1],e,s,gg)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := src.program.Body[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	scope := &wxmlScope{table: []interface{}{
+		[]interface{}{int64(3), "a"},
+		[]interface{}{int64(3), "b"},
+		[]interface{}{int64(3), "main"},
+		[]interface{}{int64(3), "box"},
+	}}
+	node := &wxmlNode{}
+	scope.attrs(node, call.ArgumentList[1])
+
+	var names []string
+	for _, attr := range node.Attrs {
+		names = append(names, fmt.Sprintf("%s=%s/%t", attr.Name, attr.Value, attr.Bare))
+	}
+	if got, want := strings.Join(names, " "), "id=main/false disabled=/true class=box/false"; got != want {
+		t.Errorf("attrs = %q, want %q", got, want)
+	}
+}
+
+func TestDecompileNegativeIndex(t *testing.T) {
+	src, err := parseJs(`
+var x=['./pages/a.wxml'];d_[x[0]]={}
+var m0=function(e,s,r,gg){
+var oB=e_[x[0]].i
+_ai(oB,x[-1],e_,x[0],1,1)
+var oC=_n('view')
+_ic(x[-1],e_,x[0],e,s,oC,gg)
+_(r,oC)
+oB.pop()
+return r
+}
+e_[x[0]]={f:m0,j:[],i:[],ti:[x[-1]],ic:[x[-1]]}
+e_[x[-1]]={f:m0,j:[],i:[],ti:[],ic:[]}
+d_[x[-1]]={}
+d_[x[-1]]["t"]=function(e,s,r,gg){
+return r
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := decompileWxml(src)
+	if len(files) != 1 || files["pages/a.wxml"] == nil {
+		t.Fatalf("decompileWxml = %v, want only pages/a.wxml", files)
+	}
+	if got, want := newWxmlWriter(4, 0).render(files["pages/a.wxml"]), "<view></view>\n"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+// TestDecompileWxml 反编译 testdata/wxml 中的视图代码，结果与同名目录中的 WXML 文件比较
+func TestDecompileWxml(t *testing.T) {
+	tests := []string{
+		"gz",       // gz$gwx 函数生成表达式表的新版编译器
+		"z",        // 表达式保存在全局变量 z 中的旧版编译器
+		"template", // 互相引用的模板文件
+	}
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			code, err := os.ReadFile(filepath.Join("testdata", "wxml", name+".js"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := parseJs(string(code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := decompileWxml(src)
+
+			dir := filepath.Join("testdata", "wxml", name)
+			want := make(map[string]string)
+			err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(dir, file)
+				want[filepath.ToSlash(rel)] = string(content)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writer := newWxmlWriter(2, 0)
+			for file := range files {
+				if _, ok := want[file]; !ok {
+					t.Errorf("unexpected file %s", file)
+				}
+			}
+			for file, content := range want {
+				root, ok := files[file]
+				if !ok {
+					t.Errorf("missing file %s", file)
+					continue
+				}
+				if got := writer.render(root); got != content {
+					t.Errorf("%s:\n%s\nwant:\n%s", file, got, content)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompileWxmlReferences(t *testing.T) {
+	code, err := os.ReadFile(filepath.Join("testdata", "wxml", "template.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := parseJs(string(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := decompileWxml(src)
+
+	// 文件 -> 引用的文件及模板，按出现顺序
+	want := map[string][]string{
+		"pages/index/index.wxml": {"import ../../common/a.wxml", "template is=ta", "include ../../components/b/b.wxml"},
+		"common/a.wxml":          {"import ../components/b/b.wxml", "template name=ta", "template is=tb"},
+		"components/b/b.wxml":    {"import ../../common/a.wxml", "template name=tb"},
+	}
+	for file, refs := range want {
+		var got []string
+		var walk func(n *wxmlNode)
+		walk = func(n *wxmlNode) {
+			switch n.Tag {
+			case "import", "include":
+				src, _ := n.attr("src")
+				got = append(got, n.Tag+" "+src)
+			case "template":
+				for _, name := range []string{"name", "is"} {
+					if value, ok := n.attr(name); ok {
+						got = append(got, "template "+name+"="+value)
+					}
+				}
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		if root := files[file]; root != nil {
+			walk(root)
+		}
+		if !reflect.DeepEqual(got, refs) {
+			t.Errorf("%s references = %q, want %q", file, got, refs)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package unpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 表达式表中的操作类型，见编译后视图代码的 _ev 函数
+const (
+	opsValue    = 1  // 字面量
+	opsOperator = 2  // 运算符
+	opsString   = 3  // 原样输出的字符串
+	opsArgs     = 4  // 函数调用的参数列表
+	opsArray    = 5  // 数组
+	opsMember   = 6  // 成员访问
+	opsVariable = 7  // 变量
+	opsObject   = 8  // 单个属性的对象
+	opsMerge    = 9  // 合并对象
+	opsSpread   = 10 // 展开对象
+	opsConcat   = 11 // 字符串拼接
+	opsCall     = 12 // 函数调用
+)
+
+// 运算符优先级，数值越大优先级越高
+var operatorPriority = map[string]int{
+	"?:": 4, "||": 5, "&&": 6, "|": 7, "^": 8, "&": 9,
+	"==": 10, "!=": 10, "===": 10, "!==": 10,
+	">": 11, "<": 11, ">=": 11, "<=": 11,
+	"<<": 12, ">>": 12, ">>>": 12,
+	"+": 13, "-": 13, "*": 14, "/": 14, "%": 14,
+	"!": 16, "~": 16,
+}
+
+// 合法的属性名，可以使用 a.b 的形式访问
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// restoreOps 将表达式表中的一项还原为 WXML 中的值，绑定的表达式以 {{}} 包裹
+func restoreOps(ops interface{}) string {
+	value, _ := restoreExpression(ops)
+	if list, ok := ops.([]interface{}); ok && len(list) > 0 {
+		if op, ok := opsNumber(list[0]); ok && (op == opsString || op == opsConcat) {
+			return value
+		}
+	}
+	return bindExpression(value)
+}
+
+// bindExpression 以 {{}} 包裹表达式，对象字面量直接在外层加一对花括号
+func bindExpression(value string) string {
+	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+		return "{" + value + "}"
+	}
+	return "{{" + value + "}}"
+}
+
+// restoreExpression 还原表达式，返回表达式代码及是否为变量名
+func restoreExpression(ops interface{}) (string, bool) {
+	list, ok := ops.([]interface{})
+	if !ok || len(list) == 0 {
+		return "", false
+	}
+
+	// 首项为数字时为字面量或字符串拼接
+	if op, ok := opsNumber(list[0]); ok {
+		switch op {
+		case opsString:
+			if len(list) > 1 {
+				return fmt.Sprint(list[1]), false
+			}
+		case opsValue:
+			if len(list) > 1 {
+				return wxon(list[1]), false
+			}
+		case opsConcat:
+			var sb strings.Builder
+			for _, item := range list[1:] {
+				sb.WriteString(restoreOps(item))
+			}
+			return sb.String(), false
+		}
+		return "", false
+	}
+
+	head, ok := list[0].([]interface{})
+	if !ok || len(head) == 0 {
+		return "", false
+	}
+	op, _ := opsNumber(head[0])
+	operand := func(i int) string {
+		if i >= len(list) {
+			return ""
+		}
+		value, _ := restoreExpression(list[i])
+		return value
+	}
+
+	switch op {
+	case opsOperator:
+		operator := ""
+		if len(head) > 1 {
+			operator, _ = head[1].(string)
+		}
+		return restoreOperator(operator, list), false
+	case opsArgs:
+		return operand(1), false
+	case opsArray:
+		switch len(list) {
+		case 1:
+			return "[]", false
+		case 2:
+			return brace(operand(1), "[", "]"), false
+		default:
+			elements := operand(1)
+			if strings.HasPrefix(elements, "[") && strings.HasSuffix(elements, "]") {
+				elements = strings.TrimSpace(elements[1 : len(elements)-1])
+				if elements == "" {
+					return brace(operand(2), "[", "]"), false
+				}
+				return brace(elements+", "+operand(2), "[", "]"), false
+			}
+			return brace("..."+elements+", "+operand(2), "[", "]"), false
+		}
+	case opsMember:
+		object := operand(1)
+		if isOperator(list[1]) {
+			object = "(" + object + ")"
+		}
+		if len(list) < 3 {
+			return object, false
+		}
+		member, isVariable := restoreExpression(list[2])
+		if !isVariable && identifierRegexp.MatchString(member) {
+			return object + "." + member, false
+		}
+		return object + brace(member, "[", "]"), false
+	case opsVariable:
+		if len(list) > 1 {
+			if name, ok := list[1].([]interface{}); ok && len(name) > 1 {
+				if kind, _ := opsNumber(name[0]); kind == opsString {
+					return fmt.Sprint(name[1]), true
+				}
+			}
+		}
+		return operand(1), false
+	case opsObject:
+		if len(list) < 3 {
+			return "{}", false
+		}
+		return brace(objectKey(list[1])+": "+operand(2), "{", "}"), false
+	case opsMerge:
+		a, b := operand(1), operand(2)
+		return brace(objectMembers(a)+", "+objectMembers(b), "{", "}"), false
+	case opsSpread:
+		return "..." + operand(1), false
+	case opsCall:
+		callee := operand(1)
+		if isOperator(list[1]) {
+			callee = "(" + callee + ")"
+		}
+		arguments := operand(2)
+		if strings.HasPrefix(arguments, "[") && strings.HasSuffix(arguments, "]") {
+			return callee + "(" + strings.TrimSpace(arguments[1:len(arguments)-1]) + ")", false
+		}
+		return callee + ".apply(null, " + arguments + ")", false
+	}
+
+	content, _ := json.Marshal(ops)
+	return fmt.Sprintf("{ __unknown: %s }", content), false
+}
+
+// restoreOperator 还原运算符表达式，优先级低于当前运算符的操作数加括号
+func restoreOperator(operator string, list []interface{}) string {
+	priority := operatorPriority[operator]
+	if operator == "-" && len(list) == 2 {
+		priority = operatorPriority["!"]
+	}
+	operand := func(i int) string {
+		if i >= len(list) {
+			return ""
+		}
+		value, _ := restoreExpression(list[i])
+		if child, ok := list[i].([]interface{}); ok && isOperator(child) {
+			childOperator := child[0].([]interface{})[1]
+			childPriority := operatorPriority[fmt.Sprint(childOperator)]
+			if childOperator == "-" && len(child) == 2 {
+				childPriority = operatorPriority["!"]
+			}
+			// 同级的运算符按结合方向加括号，条件运算符为右结合
+			if priority > childPriority || priority == childPriority && (i > 1) != (operator == "?:") {
+				value = "(" + value + ")"
+			}
+		}
+		return value
+	}
+
+	switch {
+	case operator == "?:":
+		return operand(1) + " ? " + operand(2) + " : " + operand(3)
+	case len(list) == 2:
+		return operator + operand(1)
+	default:
+		return operand(1) + " " + operator + " " + operand(2)
+	}
+}
+
+// isOperator 是否为运算符表达式
+func isOperator(ops interface{}) bool {
+	list, ok := ops.([]interface{})
+	if !ok || len(list) == 0 {
+		return false
+	}
+	head, ok := list[0].([]interface{})
+	if !ok || len(head) < 2 {
+		return false
+	}
+	op, _ := opsNumber(head[0])
+	return op == opsOperator
+}
+
+// opsNumber 读取表达式表中的数字
+func opsNumber(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), v == float64(int(v))
+	case int:
+		return v, true
+	}
+	return 0, false
+}
+
+// brace 以括号包裹内容，内容以括号开头或结尾时加空格，避免与 {{}} 混淆
+func brace(content, open, close string) string {
+	if strings.HasPrefix(content, "{") || strings.HasSuffix(content, "}") {
+		content = " " + content + " "
+	}
+	return open + content + close
+}
+
+// objectMembers 去掉对象字面量外层的花括号，展开表达式原样返回
+func objectMembers(value string) string {
+	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+		return strings.TrimSpace(value[1 : len(value)-1])
+	}
+	if strings.HasPrefix(value, "...") {
+		return value
+	}
+	return "..." + value
+}
+
+// objectKey 返回对象字面量的键，不是合法的属性名时加引号
+func objectKey(key interface{}) string {
+	name := fmt.Sprint(key)
+	if identifierRegexp.MatchString(name) {
+		return name
+	}
+	return wxon(name)
+}
+
+// wxon 将字面量转换为 WXML 表达式，字符串使用单引号，对象的键不加引号
+func wxon(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(v) + "'"
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = wxon(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = objectKey(key) + ": " + wxon(v[key])
+		}
+		return brace(strings.Join(items, ", "), "{", "}")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,400 @@
+package unpack
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dop251/goja/ast"
+)
+
+// WxsTag 页面中声明的 WXS 模块
+type WxsTag struct {
+	Module string // 模块名
+	Src    string // 外部 .wxs 文件相对于包根目录的路径，内联模块为空
+	Code   string // 内联模块的代码
+}
+
+// WxsModules 从编译后的视图代码中还原的 WXS 模块
+type WxsModules struct {
+	Files map[string]string   // .wxs 文件相对于包根目录的路径 -> 代码
+	Tags  map[string][]WxsTag // .wxml 文件相对于包根目录的路径 -> 声明的模块，按声明顺序排列
+}
+
+// 编译后的模块名，p_ 为外部 .wxs 文件，m_ 为 .wxml 中的内联模块
+const (
+	wxsFilePrefix   = "p_"
+	wxsInlinePrefix = "m_"
+)
+
+// nv_require('p_./a.wxs')() 形式的模块引用
+var wxsRequireRegexp = regexp.MustCompile(`nv_require\(\s*(["'])p_([^"']+)["']\s*\)\s*\(\s*\)`)
+
+// extractWxs 从视图代码中还原所有 WXS 模块及页面中的 <wxs> 声明
+// 编译后的模块为 np_ 开头的函数，由 nv_require 中的 nnm 表按模块名索引，页面通过 f_ 引用模块
+func extractWxs(src *jsSource) *WxsModules {
+	modules := &WxsModules{Files: make(map[string]string), Tags: make(map[string][]WxsTag)}
+
+	src.eachBody(func(list []ast.Statement) {
+		// 模块函数及模块名
+		functions := make(map[string]*ast.FunctionLiteral)
+		names := make(map[string]string)
+		for _, statement := range list {
+			switch st := statement.(type) {
+			case *ast.FunctionDeclaration:
+				if name := identName(st.Function.Name); strings.HasPrefix(name, "np_") {
+					functions[name] = st.Function
+				}
+			case *ast.VariableStatement:
+				for _, binding := range st.List {
+					if identName(binding.Target) == "nv_require" {
+						for module, function := range wxsModuleTable(binding.Initializer) {
+							names[module] = function
+						}
+					}
+				}
+			}
+		}
+		if len(names) == 0 {
+			return
+		}
+
+		// 还原模块代码
+		codes := make(map[string]string)
+		for module, function := range names {
+			literal, ok := functions[function]
+			if !ok {
+				continue
+			}
+			codes[module] = wxsCode(src, literal, wxsModuleDir(module))
+		}
+		for module, code := range codes {
+			if file, ok := strings.CutPrefix(module, wxsFilePrefix); ok {
+				modules.Files[normalizePath(file)] = code
+			}
+		}
+
+		// 页面中的模块声明，f_['./a.wxml']['name'] = nv_require('m_./a.wxml:name') 或 f_['./b.wxs'] || nv_require('p_./b.wxs')
+		for _, statement := range list {
+			page, name, module, ok := wxsAssignment(statement)
+			if !ok {
+				continue
+			}
+			tag := WxsTag{Module: name}
+			if file, ok := strings.CutPrefix(module, wxsFilePrefix); ok {
+				tag.Src = normalizePath(file)
+			} else {
+				tag.Code = codes[module]
+			}
+			page = normalizePath(page)
+			modules.Tags[page] = append(modules.Tags[page], tag)
+		}
+	})
+
+	return modules
+}
+
+// wxsModuleTable 读取 nv_require 中 nnm 表的模块名及对应的函数名
+func wxsModuleTable(initializer ast.Expression) map[string]string {
+	table := make(map[string]string)
+	call, ok := initializer.(*ast.CallExpression)
+	if !ok {
+		return table
+	}
+	function, ok := call.Callee.(*ast.FunctionLiteral)
+	if !ok || function.Body == nil {
+		return table
+	}
+	for _, statement := range function.Body.List {
+		variables, ok := statement.(*ast.VariableStatement)
+		if !ok {
+			continue
+		}
+		for _, binding := range variables.List {
+			object, ok := binding.Initializer.(*ast.ObjectLiteral)
+			if identName(binding.Target) != "nnm" || !ok {
+				continue
+			}
+			for _, property := range object.Value {
+				keyed, ok := property.(*ast.PropertyKeyed)
+				if !ok {
+					continue
+				}
+				if module, ok := stringValue(keyed.Key); ok {
+					table[module] = identName(keyed.Value)
+				}
+			}
+		}
+	}
+	return table
+}
+
+// wxsAssignment 解析页面引用模块的赋值语句，返回页面路径、模块名及编译后的模块名
+func wxsAssignment(statement ast.Statement) (page, name, module string, ok bool) {
+	expression, isExpression := statement.(*ast.ExpressionStatement)
+	if !isExpression {
+		return
+	}
+	assign, isAssign := expression.Expression.(*ast.AssignExpression)
+	if !isAssign {
+		return
+	}
+	outer, isBracket := assign.Left.(*ast.BracketExpression)
+	if !isBracket {
+		return
+	}
+	inner, isBracket := outer.Left.(*ast.BracketExpression)
+	if !isBracket || identName(inner.Left) != "f_" {
+		return
+	}
+	page, okPage := stringValue(inner.Member)
+	name, okName := stringValue(outer.Member)
+	if !okPage || !okName {
+		return
+	}
+
+	right := assign.Right
+	if binary, isOr := isLogicalOr(right); isOr {
+		right = binary.Right
+	}
+	callee, call := callOf(right)
+	if callee != "nv_require" || len(call.ArgumentList) == 0 {
+		return
+	}
+	module, ok = stringValue(call.ArgumentList[0])
+	return
+}
+
+// wxsModuleDir 返回模块所在目录，内联模块为页面所在目录
+func wxsModuleDir(module string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(module, wxsFilePrefix), wxsInlinePrefix)
+	if i := strings.LastIndex(name, ":"); i >= 0 && strings.HasPrefix(module, wxsInlinePrefix) {
+		name = name[:i]
+	}
+	return path.Dir(normalizePath(name))
+}
+
+// wxsCode 将编译后的模块函数还原为 WXS 代码
+// 去掉 nv_module 的声明和返回语句，将引用的模块改回相对路径的 require，并去掉标识符的 nv_ 前缀
+func wxsCode(src *jsSource, function *ast.FunctionLiteral, dir string) string {
+	list := function.Body.List
+	from, to := 0, len(list)
+	if from < to && isNvModuleDeclaration(list[from]) {
+		from++
+	}
+	if from < to {
+		if ret, ok := list[to-1].(*ast.ReturnStatement); ok && strings.Contains(src.text(ret), "nv_module") {
+			to--
+		}
+	}
+	if from >= to {
+		return ""
+	}
+
+	// 语法树不保留括号，按语句的起始位置截取代码，避免丢失末尾的括号及分号
+	end := int(function.Body.RightBrace)
+	if to < len(list) {
+		end = int(list[to].Idx0())
+	}
+	// 从 nv_module 的声明之后截取，保留第一条语句之前的注释及缩进，去掉开头的空行
+	start := int(function.Body.LeftBrace) + 1
+	if from > 0 {
+		start = int(list[from-1].Idx1())
+	}
+	code := strings.TrimLeft(src.slice(start, end), ";")
+	for {
+		line, rest, ok := strings.Cut(code, "\n")
+		if !ok || strings.TrimSpace(line) != "" {
+			break
+		}
+		code = rest
+	}
+	code = dedent(strings.TrimRight(code, " \t\r\n"))
+
+	code = wxsRequireRegexp.ReplaceAllStringFunc(code, func(match string) string {
+		groups := wxsRequireRegexp.FindStringSubmatch(match)
+		return fmt.Sprintf("require(%s%s%s)", groups[1], relativePath(dir, normalizePath(groups[2])), groups[1])
+	})
+	return stripNvPrefix(code) + "\n"
+}
+
+// dedent 去掉所有非空行共同的缩进
+func dedent(code string) string {
+	lines := strings.Split(code, "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isNvModuleDeclaration 是否为 var nv_module={nv_exports:{}} 声明
+func isNvModuleDeclaration(statement ast.Statement) bool {
+	variables, ok := statement.(*ast.VariableStatement)
+	return ok && len(variables.List) == 1 && identName(variables.List[0].Target) == "nv_module"
+}
+
+// stripNvPrefix 去掉标识符的 nv_ 前缀，字符串、注释及正则表达式字面量中的内容不变
+func stripNvPrefix(code string) string {
+	var sb strings.Builder
+	last := -1 // 上一个有效字符的位置，用于区分除号与正则表达式
+	for i := 0; i < len(code); {
+		c := code[i]
+		end := i
+		switch {
+		case c == '"' || c == '\'':
+			end = quotedEnd(code, i)
+			last = end - 1
+		case strings.HasPrefix(code[i:], "//"):
+			end = len(code)
+			if n := strings.IndexByte(code[i:], '\n'); n >= 0 {
+				end = i + n
+			}
+		case strings.HasPrefix(code[i:], "/*"):
+			end = len(code)
+			if n := strings.Index(code[i+2:], "*/"); n >= 0 {
+				end = i + 2 + n + 2
+			}
+		case c == '/' && regexpAllowed(code, last):
+			end = regexpEnd(code, i)
+			last = end - 1
+		case strings.HasPrefix(code[i:], "nv_") && (i == 0 || !isIdentByte(code[i-1])):
+			i += len("nv_")
+			continue
+		}
+		if end > i {
+			sb.WriteString(code[i:end])
+			i = end
+			continue
+		}
+		sb.WriteByte(c)
+		if !isIndentByte(c) && c != '\n' && c != '\r' {
+			last = i
+		}
+		i++
+	}
+	return sb.String()
+}
+
+// quotedEnd 返回从 start 开始的字符串字面量的结束位置
+func quotedEnd(code string, start int) int {
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case code[start], '\n':
+			return i + 1
+		}
+	}
+	return len(code)
+}
+
+// 之后的 / 为正则表达式开始的字符及关键字
+const regexpPrecedingBytes = "(,=:[!&|?{};+-*%<>~^"
+
+var regexpPrecedingKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "in": true, "void": true, "delete": true, "throw": true, "new": true,
+}
+
+// regexpAllowed 位于 last 之后的 / 是否为正则表达式的开始，否则为除号
+func regexpAllowed(code string, last int) bool {
+	if last < 0 {
+		return true
+	}
+	if strings.IndexByte(regexpPrecedingBytes, code[last]) >= 0 {
+		return true
+	}
+	start := last
+	for start > 0 && isIdentByte(code[start-1]) {
+		start--
+	}
+	return isIdentByte(code[last]) && regexpPrecedingKeywords[code[start:last+1]]
+}
+
+// regexpEnd 返回从 start 开始的正则表达式字面量及标志的结束位置，不是正则表达式时返回 start+1
+func regexpEnd(code string, start int) int {
+	inClass := false
+	for i := start + 1; i < len(code); i++ {
+		switch c := code[i]; {
+		case c == '\\':
+			i++
+		case c == '\n':
+			return start + 1
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			i++
+			for i < len(code) && isIdentByte(code[i]) {
+				i++
+			}
+			return i
+		}
+	}
+	return start + 1
+}
+
+// isIndentByte 是否为缩进中的字符
+func isIndentByte(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// isIdentByte 是否为标识符中的字符
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// normalizePath 将编译产物中的 ./a/b 或 /a/b 形式的路径转换为相对于包根目录的 a/b
+func normalizePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// relativePath 返回从 dir 目录到 target 的相对路径，同级文件以 ./ 开头
+func relativePath(dir, target string) string {
+	from := strings.Split(path.Clean("/"+dir), "/")[1:]
+	to := strings.Split(path.Clean("/"+target), "/")[1:]
+	if len(from) == 1 && from[0] == "" {
+		from = nil
+	}
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	rel := strings.Repeat("../", len(from)-i) + strings.Join(to[i:], "/")
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// wxsNodes 生成页面开头的 <wxs> 声明，由 wxmlWriter 序列化
+func wxsNodes(page string, tags []WxsTag) []*wxmlNode {
+	nodes := make([]*wxmlNode, 0, len(tags))
+	dir := path.Dir(page)
+	for _, tag := range tags {
+		n := &wxmlNode{Tag: "wxs"}
+		if tag.Src != "" {
+			n.Attrs = []wxmlAttr{{Name: "src", Value: relativePath(dir, tag.Src)}, {Name: "module", Value: tag.Module}}
+		} else {
+			n.Attrs = []wxmlAttr{{Name: "module", Value: tag.Module}}
+			n.Children = []*wxmlNode{{Text: tag.Code}}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
@@ -0,0 +1,154 @@
+package unpack
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dop251/goja/ast"
+)
+
+func TestStripNvPrefix(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`var nv_a = nv_b.nv_c(nv_d);`, `var a = b.c(d);`},
+		{`var nv_a = 'nv_b' + "nv_\"c" + nv_d;`, `var a = 'nv_b' + "nv_\"c" + d;`},
+		{`var snv_a = a_nv_b;`, `var snv_a = a_nv_b;`},
+		{"nv_a(); // nv_b\nnv_c();", "a(); // nv_b\nc();"},
+		{"nv_a(/* nv_b */ nv_c);", "a(/* nv_b */ c);"},
+		{`var nv_r = /nv_\d+[/]nv_/g, nv_s = nv_r;`, `var r = /nv_\d+[/]nv_/g, s = r;`},
+		{`return /nv_a/.test(nv_b);`, `return /nv_a/.test(b);`},
+		{`var nv_x = nv_a / nv_b / nv_c;`, `var x = a / b / c;`},
+		{`var nv_x = (nv_a) / 2 + nv_b[0] / nv_c;`, `var x = (a) / 2 + b[0] / c;`},
+		{"var nv_x = nv_a /\nnv_b;", "var x = a /\nb;"},
+	}
+	for _, tt := range tests {
+		if got := stripNvPrefix(tt.code); got != tt.want {
+			t.Errorf("stripNvPrefix(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		dir    string
+		target string
+		want   string
+	}{
+		{"pages/index", "pages/index/a.wxs", "./a.wxs"},
+		{"pages/index", "utils/a.wxs", "../../utils/a.wxs"},
+		{"pages/index", "pages/other/a.wxs", "../other/a.wxs"},
+		{".", "utils/a.wxs", "./utils/a.wxs"},
+		{"", "a.wxs", "./a.wxs"},
+		{"utils", "a.wxs", "../a.wxs"},
+		{"/pages/index/", "./pages/index/sub/a.wxs", "./sub/a.wxs"},
+		{"a/b", "a/b", "../b"},
+	}
+	for _, tt := range tests {
+		if got := relativePath(tt.dir, tt.target); got != tt.want {
+			t.Errorf("relativePath(%q, %q) = %q, want %q", tt.dir, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestWxsCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		dir  string
+		want string
+	}{
+		{
+			name: "single line",
+			code: `function np_0(){var nv_module={nv_exports:{}};var nv_double = (function (nv_n){return(nv_n * 2)});nv_module.nv_exports = ({nv_double:nv_double,});return nv_module.nv_exports;}`,
+			dir:  "pages/index",
+			want: "var double = (function (n){return(n * 2)});module.exports = ({double:double,});\n",
+		},
+		{
+			name: "multiple lines",
+			code: "function np_1(){var nv_module={nv_exports:{}};\n" +
+				"  // nv_require is kept\n" +
+				"  var nv_a = nv_require('p_./utils/a.wxs')();\n" +
+				"  nv_module.nv_exports = nv_a;\n" +
+				"return nv_module.nv_exports;}",
+			dir: "pages/index",
+			want: "// nv_require is kept\n" +
+				"var a = require('../../utils/a.wxs');\n" +
+				"module.exports = a;\n",
+		},
+		{
+			name: "empty",
+			code: `function np_2(){var nv_module={nv_exports:{}};return nv_module.nv_exports;}`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := parseJs(tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			function := src.program.Body[0].(*ast.FunctionDeclaration).Function
+			if got := wxsCode(src, function, tt.dir); got != tt.want {
+				t.Errorf("wxsCode =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractWxs(t *testing.T) {
+	code, err := os.ReadFile(filepath.Join("testdata", "wxml", "wxs.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := parseJs(string(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := extractWxs(src)
+
+	wantFiles := map[string]string{
+		"common/pad.wxs": "// pad: nv_0 prefix is kept in comments\n" +
+			"function pad(n) {\n" +
+			"  /* nv_n < 10 */\n" +
+			"  return n < 10 ? 'nv_0' + n : '' + n;\n" +
+			"};\n" +
+			"module.exports = ({\n" +
+			"  pad: pad,\n" +
+			"});\n",
+		"utils/format.wxs": "var pad = require('../common/pad.wxs');\n" +
+			"var reg = /nv_\\d+[/]/g;\n" +
+			"function fmt(n) {\n" +
+			"  return pad.pad(n / 2) + reg.source;\n" +
+			"};\n" +
+			"module.exports = ({\n" +
+			"  fmt: fmt,\n" +
+			"});\n",
+	}
+	if !reflect.DeepEqual(modules.Files, wantFiles) {
+		t.Errorf("Files = %q, want %q", modules.Files, wantFiles)
+	}
+
+	wantTags := map[string][]WxsTag{
+		"pages/index/index.wxml": {
+			{Module: "tools", Code: "var double = (function (n){return(n * 2)});module.exports = ({double:double,});\n"},
+			{Module: "fmt", Src: "utils/format.wxs"},
+		},
+	}
+	if !reflect.DeepEqual(modules.Tags, wantTags) {
+		t.Errorf("Tags = %+v, want %+v", modules.Tags, wantTags)
+	}
+
+	nodes := wxsNodes("pages/index/index.wxml", modules.Tags["pages/index/index.wxml"])
+	got := newWxmlWriter(2, 0).render(&wxmlNode{Children: nodes})
+	want := "<wxs module=\"tools\">\n" +
+		"  var double = (function (n){return(n * 2)});module.exports = ({double:double,});\n" +
+		"</wxs>\n" +
+		"<wxs src=\"../../utils/format.wxs\" module=\"fmt\" />\n"
+	if got != want {
+		t.Errorf("wxsNodes =\n%s\nwant:\n%s", got, want)
+	}
+}
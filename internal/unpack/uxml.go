@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -12,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
 	"github.com/dop251/goja"
 )
 
@@ -19,6 +19,12 @@ type XmlParser struct {
 	OutputDir string
 	// 解析器版本
 	Version string
+	// 输出目标，为空时直接写入磁盘
+	Output sink.Sink
+	// 缩进的空格数，0 表示使用制表符
+	Indent int
+	// 单行最大宽度，超出时属性分行，0 表示不限制
+	Width int
 }
 
 // 获取生成函数
@@ -64,88 +70,14 @@ func getFinalResult(vm *goja.Runtime, value goja.Value) (goja.Value, error) {
 	return value, nil
 }
 
-// 生成视图代码
-func getDomTree(node interface{}) string {
-	// 用于构建 XML 字符串的函数
-	var processNodes func(node map[string]interface{}, indentLevel int, isRoot bool) string
-	processNodes = func(node map[string]interface{}, indentLevel int, isRoot bool) string {
-		var sb strings.Builder
-
-		// 生成缩进
-		indent := strings.Repeat("\t", indentLevel)
-
-		// 获取标签名称
-		tag, ok := node["tag"].(string)
-		if !ok {
-			return ""
-		}
-		tag = strings.TrimPrefix(tag, "wx-") // 去除前缀 wx-
-
-		// 如果是根节点，不添加开始标签
-		if !isRoot {
-			// 开始标签
-			sb.WriteString(indent)
-			sb.WriteString("<")
-			sb.WriteString(tag)
-
-			// 处理属性
-			if attr, ok := node["attr"].(map[string]interface{}); ok {
-				for key, value := range attr {
-					key = strings.TrimPrefix(key, "$wxs:")
-					if strings.HasPrefix(key, "$") {
-						continue
-					}
-					if value == nil {
-						sb.WriteString(fmt.Sprintf(" %s=\"\"", key))
-					} else {
-						sb.WriteString(fmt.Sprintf(" %s=\"%v\"", key, value))
-					}
-				}
-			}
-
-			// 结束标签
-			sb.WriteString(">")
-		}
-
-		// 处理子节点
-		if children, ok := node["children"].([]interface{}); ok {
-			if len(children) > 0 && !isRoot {
-				sb.WriteString("\n")
-			}
-			for _, child := range children {
-				if childMap, ok := child.(map[string]interface{}); ok {
-					sb.WriteString(processNodes(childMap, indentLevel+1, false))
-				} else {
-					// 如果 children 是字符串且字符串为空，则不换行
-					if str, ok := child.(string); ok {
-						if str != "" {
-							sb.WriteString(strings.Repeat("\t", indentLevel+1))
-							sb.WriteString(str + "\n")
-						}
-					}
-				}
-			}
-		}
-
-		// 结束标签（如果不是根节点）
-		if !isRoot {
-			sb.WriteString(indent)
-			sb.WriteString("</")
-			sb.WriteString(tag)
-			sb.WriteString(">\n")
-		}
-
-		return sb.String()
-	}
-
-	// 将根节点转换为 map
-	rootNode, ok := node.(map[string]interface{})
-	if !ok {
+// 生成视图代码，运行结果中的虚拟节点只保留子节点
+func getDomTree(node interface{}, writer *wxmlWriter) string {
+	root := domNode(node)
+	if root == nil {
 		return ""
 	}
-
-	// 生成并返回最终的 XML 字符串，不包括根标签
-	return processNodes(rootNode, 0, true)
+	simplify(root)
+	return writer.render(root)
 }
 
 func getXml(path string, scriptCode, gencode string, results chan<- map[string]interface{}, wg *sync.WaitGroup, version string, sem chan struct{}) {
@@ -256,7 +188,8 @@ func (p *XmlParser) Parse(option config.WxapkgInfo) error {
 	const maxConcurrent = 5
 	sem := make(chan struct{}, maxConcurrent)
 
-	code, err := os.ReadFile(frameFile)
+	output := outputOf(p.Output)
+	code, err := output.ReadFile(frameFile)
 	if err != nil {
 		log.Printf("Error reading file: %v\n", err)
 		return err
@@ -285,10 +218,23 @@ var setCssToHead=function(file,_xcInvalid,info){return ()=>{}};`
 	// 正则匹配生成函数
 	getFuc(scriptCode, gwx)
 
+	// 还原 WXS 模块，并反编译 WXML 的表达式及控制语句
+	var wxs *WxsModules
+	var decompiled map[string]*wxmlNode
+	if src, err := parseJs(scriptCode); err != nil {
+		log.Printf("Error parsing %s: %v\n", frameFile, err)
+	} else {
+		wxs = extractWxs(src)
+		decompiled = decompileWxml(src)
+	}
+
 	scriptCode = patch + scriptCode
 
-	// 运行生成函数
+	// 运行生成函数，无法反编译的页面使用运行结果
 	for path, gencode := range gwx {
+		if _, ok := decompiled[normalizePath(path)]; ok {
+			continue
+		}
 		wg.Add(1)
 		go getXml(path, scriptCode, gencode.(string), results, &wg, p.Version, sem)
 	}
@@ -298,20 +244,42 @@ var setCssToHead=function(file,_xcInvalid,info){return ()=>{}};`
 		close(results)
 	}()
 
+	writer := newWxmlWriter(p.Indent, p.Width)
 	finalResults := make(map[string]string)
 	for result := range results {
 		for k, v := range result {
-			finalResults[k] = getDomTree(v)
+			finalResults[k] = getDomTree(v, writer)
+		}
+	}
+	for name, root := range decompiled {
+		finalResults[name] = writer.render(root)
+	}
+
+	// 在页面开头插入 <wxs> 声明，并保存外部 .wxs 文件
+	if wxs != nil {
+		for name, code := range wxs.Files {
+			err = save(output, saveDir, name, []byte(code))
+			if err != nil {
+				log.Printf("Error saving file: %v\n", err)
+			}
+		}
+		for page, tags := range wxs.Tags {
+			name := page
+			for result := range finalResults {
+				if normalizePath(result) == page {
+					name = result
+				}
+			}
+			finalResults[name] = writer.render(&wxmlNode{Children: wxsNodes(page, tags)}) + finalResults[name]
 		}
 	}
 
 	for name, content := range finalResults {
-		name = filepath.Join(saveDir, name)
-		err = save(name, []byte(content))
+		err = save(output, saveDir, name, []byte(content))
 		if err != nil {
 			log.Printf("Error saving file: %v\n", err)
 		}
-		log.Printf("Saved file: %s\n", name)
+		log.Printf("Saved file: %s\n", filepath.Join(saveDir, name))
 	}
 
 	return nil
@@ -3,7 +3,6 @@ package unpack
 import (
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -11,6 +10,7 @@ import (
 	"github.com/Ackites/KillWxapkg/internal/enum"
 
 	"github.com/Ackites/KillWxapkg/internal/config"
+	"github.com/Ackites/KillWxapkg/internal/sink"
 
 	"github.com/Ackites/KillWxapkg/internal/util"
 	"github.com/dop251/goja"
@@ -20,6 +20,8 @@ import (
 // XssParser 结构体定义
 type XssParser struct {
 	OutputDir string
+	Deletion  *config.FileDeletionManager // 会话的待删除文件列表
+	Output    sink.Sink                   // 输出目标，为空时直接写入磁盘
 }
 
 // 相对路径转换
@@ -196,8 +198,10 @@ func (p *XssParser) Parse(option config.WxapkgInfo) error {
 		saveDir = p.OutputDir
 	}
 
-	// 创建文件删除管理器
-	manager := config.NewFileDeletionManager()
+	// 文件删除管理器
+	manager := p.Deletion
+
+	output := outputOf(p.Output)
 
 	var runList = make(map[string]string)
 	var result = make(map[string]string)
@@ -212,7 +216,7 @@ func (p *XssParser) Parse(option config.WxapkgInfo) error {
 		}
 
 		for _, name := range files {
-			code, err := os.ReadFile(name)
+			code, err := output.ReadFile(name)
 			if err != nil {
 				log.Printf("Error reading file: %v\n", err)
 				continue
@@ -240,10 +244,10 @@ func (p *XssParser) Parse(option config.WxapkgInfo) error {
 	}
 
 	// 扫描目录中的所有 HTML 文件
-	scanHtml(saveDir, manager, func(files []string) {
+	scanHtml(output, saveDir, manager, func(files []string) {
 		var frameFile = option.Option.ViewSource
 
-		code, err := os.ReadFile(frameFile)
+		code, err := output.ReadFile(frameFile)
 		if err != nil {
 			log.Printf("Error reading file: %v\n", err)
 			return
@@ -259,12 +263,12 @@ func (p *XssParser) Parse(option config.WxapkgInfo) error {
 		preRun(saveDir, scriptCode, files, func() {
 			runOnce()
 			for name, content := range result {
-				name = filepath.Join(saveDir, changeExt(name, ".wxss"))
-				err = save(name, []byte(util.TransformCSS(content)))
+				name = changeExt(name, ".wxss")
+				err = save(output, saveDir, name, []byte(util.TransformCSS(content)))
 				if err != nil {
 					log.Printf("Error saving file: %v\n", err)
 				}
-				log.Printf("Saved file: %s\n", name)
+				log.Printf("Saved file: %s\n", filepath.Join(saveDir, name))
 			}
 		})
 	})
@@ -273,13 +277,14 @@ func (p *XssParser) Parse(option config.WxapkgInfo) error {
 }
 
 // scanHtml 扫描目录中的Html文件并返回文件列表
-func scanHtml(dir string, manager *config.FileDeletionManager, cb func([]string)) {
+func scanHtml(output sink.Sink, dir string, manager *config.FileDeletionManager, cb func([]string)) {
 	var files []string
 	// 删除相关的JS文件
 	suffixes := []string{".appservice.js", ".common.js", ".webview.js"}
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".html") {
-			if info.Name() != enum.PageFrameHtml {
+	err := output.Walk(dir, func(path string) error {
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, ".html") {
+			if name != enum.PageFrameHtml {
 				files = append(files, path)
 				for _, suffix := range suffixes {
 					jsFile := strings.TrimSuffix(path, ".html") + suffix
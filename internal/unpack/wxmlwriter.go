@@ -0,0 +1,221 @@
+package unpack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 没有子节点的组件，输出为自闭合标签
+var voidTags = map[string]bool{
+	"import": true, "include": true, "template": true, "wxs": true,
+	"image": true, "input": true, "textarea": true, "icon": true, "progress": true,
+	"checkbox": true, "radio": true, "switch": true, "slider": true,
+	"web-view": true, "open-data": true, "rich-text": true, "editor": true,
+	"official-account": true, "ad": true, "ad-custom": true,
+}
+
+// wxmlWriter 将节点序列化为 WXML 代码
+type wxmlWriter struct {
+	indent string // 每级缩进
+	width  int    // 单行最大宽度，超出时属性分行，0 表示不限制
+}
+
+// newWxmlWriter 创建序列化器，indent 为缩进的空格数，0 表示使用制表符
+func newWxmlWriter(indent, width int) *wxmlWriter {
+	w := &wxmlWriter{indent: "\t", width: width}
+	if indent > 0 {
+		w.indent = strings.Repeat(" ", indent)
+	}
+	return w
+}
+
+// 内容为脚本代码的标签，文本不转义
+var rawTextTags = map[string]bool{
+	"wxs": true,
+}
+
+// render 生成 WXML 代码，不包括根节点
+func (w *wxmlWriter) render(root *wxmlNode) string {
+	var sb strings.Builder
+	for _, child := range root.Children {
+		w.write(&sb, child, 0, false)
+	}
+	return sb.String()
+}
+
+// write 按缩进层级写入节点，raw 为 true 时文本不转义
+func (w *wxmlWriter) write(sb *strings.Builder, n *wxmlNode, level int, raw bool) {
+	indent := strings.Repeat(w.indent, level)
+	if n.Tag == "" {
+		w.writeText(sb, n.Text, indent, raw)
+		return
+	}
+	raw = rawTextTags[n.Tag]
+
+	// 开始标签，超出宽度时每个属性单独一行
+	attrs := make([]string, len(n.Attrs))
+	for i, a := range n.Attrs {
+		attrs[i] = a.Name
+		if !a.Bare {
+			attrs[i] += "=\"" + escapeAttr(a.Value) + "\""
+		}
+	}
+	open := "<" + n.Tag
+	if len(attrs) > 0 {
+		open += " " + strings.Join(attrs, " ")
+	}
+	wrap := len(attrs) > 1 && w.exceeds(indent+open+">")
+	if wrap {
+		open = "<" + n.Tag
+		for _, a := range attrs {
+			open += "\n" + indent + w.indent + a
+		}
+		open += "\n" + indent
+	}
+
+	// 没有子节点
+	if len(n.Children) == 0 {
+		if voidTags[n.Tag] {
+			if wrap {
+				sb.WriteString(indent + open + "/>\n")
+			} else {
+				sb.WriteString(indent + open + " />\n")
+			}
+			return
+		}
+		sb.WriteString(indent + open + "></" + n.Tag + ">\n")
+		return
+	}
+
+	// 只有一行文本时与标签写在同一行
+	if only := n.Children[0]; len(n.Children) == 1 && only.Tag == "" && !strings.Contains(only.Text, "\n") {
+		text := only.Text
+		if !raw {
+			text = escapeText(text)
+		}
+		line := indent + open + ">" + text + "</" + n.Tag + ">"
+		if !w.exceeds(line[strings.LastIndex(line, "\n")+1:]) {
+			sb.WriteString(line + "\n")
+			return
+		}
+	}
+
+	sb.WriteString(indent + open + ">\n")
+	for _, child := range n.Children {
+		w.write(sb, child, level+1, raw)
+	}
+	sb.WriteString(indent + "</" + n.Tag + ">\n")
+}
+
+// writeText 写入文本，多行文本的每一行都按当前层级缩进，空行不缩进
+func (w *wxmlWriter) writeText(sb *strings.Builder, text, indent string, raw bool) {
+	if !raw {
+		text = escapeText(text)
+	}
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(indent + line + "\n")
+	}
+}
+
+// exceeds 一行是否超出最大宽度，制表符按 4 个字符计算
+func (w *wxmlWriter) exceeds(line string) bool {
+	if w.width <= 0 {
+		return false
+	}
+	return utf8.RuneCountInString(strings.ReplaceAll(line, "\t", "    ")) > w.width
+}
+
+// escapeText 转义文本中 {{}} 之外的特殊字符
+func escapeText(text string) string {
+	return escapeOutsideBinding(text, strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;"), nil)
+}
+
+// escapeAttr 转义属性值中的特殊字符，{{}} 中只转义双引号
+func escapeAttr(value string) string {
+	return escapeOutsideBinding(value, strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;"), strings.NewReplacer(`"`, "&quot;"))
+}
+
+// escapeOutsideBinding 分别转义 {{}} 之外及之内的内容，inside 为空时 {{}} 中的内容不转义
+func escapeOutsideBinding(value string, outside, inside *strings.Replacer) string {
+	var sb strings.Builder
+	for value != "" {
+		start := strings.Index(value, "{{")
+		if start < 0 {
+			sb.WriteString(outside.Replace(value))
+			break
+		}
+		end := strings.Index(value[start:], "}}")
+		if end < 0 {
+			sb.WriteString(outside.Replace(value))
+			break
+		}
+		end += start + 2
+		// 对象字面量 {{ {a:1} }} 以 }}} 结尾
+		for end < len(value) && value[end] == '}' {
+			end++
+		}
+		sb.WriteString(outside.Replace(value[:start]))
+		if inside != nil {
+			sb.WriteString(inside.Replace(value[start:end]))
+		} else {
+			sb.WriteString(value[start:end])
+		}
+		value = value[end:]
+	}
+	return sb.String()
+}
+
+// domNode 将运行生成函数得到的节点树转换为节点，属性按名称排序
+func domNode(node interface{}) *wxmlNode {
+	switch v := node.(type) {
+	case string:
+		return &wxmlNode{Text: v}
+	case map[string]interface{}:
+		tag, ok := v["tag"].(string)
+		if !ok {
+			return nil
+		}
+		// 去除前缀 wx-
+		tag = strings.TrimPrefix(tag, "wx-")
+		n := &wxmlNode{Tag: tag, virtual: tag == "virtual"}
+
+		if attr, ok := v["attr"].(map[string]interface{}); ok {
+			names := make([]string, 0, len(attr))
+			for key := range attr {
+				names = append(names, key)
+			}
+			sort.Strings(names)
+			for _, key := range names {
+				name := strings.TrimPrefix(key, "$wxs:")
+				if strings.HasPrefix(name, "$") {
+					continue
+				}
+				if attr[key] == nil {
+					n.Attrs = append(n.Attrs, wxmlAttr{Name: name, Bare: true})
+				} else {
+					n.Attrs = append(n.Attrs, wxmlAttr{Name: name, Value: fmt.Sprint(attr[key])})
+				}
+			}
+		}
+
+		if children, ok := v["children"].([]interface{}); ok {
+			for _, child := range children {
+				if c := domNode(child); c != nil {
+					n.Children = append(n.Children, c)
+				}
+			}
+		}
+		return n
+	}
+	return nil
+}
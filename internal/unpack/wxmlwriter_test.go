@@ -0,0 +1,118 @@
+package unpack
+
+import "testing"
+
+func TestWxmlWriter(t *testing.T) {
+	text := func(s string) *wxmlNode { return &wxmlNode{Text: s} }
+	elem := func(tag string, attrs []wxmlAttr, children ...*wxmlNode) *wxmlNode {
+		return &wxmlNode{Tag: tag, Attrs: attrs, Children: children}
+	}
+
+	tests := []struct {
+		name   string
+		indent int
+		width  int
+		nodes  []*wxmlNode
+		want   string
+	}{
+		{
+			name:  "escape attribute",
+			nodes: []*wxmlNode{elem("view", []wxmlAttr{{Name: "title", Value: `say "hi" <b> & {{a ? "x" : 'y'}}`}})},
+			want:  `<view title="say &quot;hi&quot; &lt;b> &amp; {{a ? &quot;x&quot; : 'y'}}"></view>` + "\n",
+		},
+		{
+			name:  "escape text",
+			nodes: []*wxmlNode{elem("text", nil, text(`a < b & "c" {{a < b && c > d}}`))},
+			want:  `<text>a &lt; b &amp; "c" {{a < b && c > d}}</text>` + "\n",
+		},
+		{
+			name:  "unterminated binding",
+			nodes: []*wxmlNode{elem("text", nil, text(`{{a < b`))},
+			want:  `<text>{{a &lt; b</text>` + "\n",
+		},
+		{
+			name:  "object literal attribute",
+			nodes: []*wxmlNode{elem("template", []wxmlAttr{{Name: "data", Value: `{{a: {b: "x"}}} "<"`}})},
+			want:  `<template data="{{a: {b: &quot;x&quot;}}} &quot;&lt;&quot;" />` + "\n",
+		},
+		{
+			name:  "object literal text",
+			nodes: []*wxmlNode{elem("text", nil, text(`{{ {a: "<"} }}}<`))},
+			want:  `<text>{{ {a: "<"} }}}&lt;</text>` + "\n",
+		},
+		{
+			name: "void tags",
+			nodes: []*wxmlNode{
+				elem("image", []wxmlAttr{{Name: "src", Value: "a.png"}}),
+				elem("input", []wxmlAttr{{Name: "disabled", Bare: true}}),
+				elem("view", nil),
+				elem("import", []wxmlAttr{{Name: "src", Value: "../a.wxml"}}),
+			},
+			want: "<image src=\"a.png\" />\n<input disabled />\n<view></view>\n<import src=\"../a.wxml\" />\n",
+		},
+		{
+			name:   "raw wxs",
+			indent: 2,
+			nodes: []*wxmlNode{elem("view", nil,
+				elem("wxs", []wxmlAttr{{Name: "module", Value: "m"}}, text("var a = 1 < 2 && \"x\";\n\nmodule.exports = a;\n")),
+				elem("wxs", []wxmlAttr{{Name: "module", Value: "n"}}, text("var b = '<b>' && c;")),
+			)},
+			want: "<view>\n" +
+				"  <wxs module=\"m\">\n" +
+				"    var a = 1 < 2 && \"x\";\n" +
+				"\n" +
+				"    module.exports = a;\n" +
+				"  </wxs>\n" +
+				"  <wxs module=\"n\">var b = '<b>' && c;</wxs>\n" +
+				"</view>\n",
+		},
+		{
+			name:  "tab indent",
+			nodes: []*wxmlNode{elem("view", nil, elem("text", nil, text("a")), text("b\nc"))},
+			want:  "<view>\n\t<text>a</text>\n\tb\n\tc\n</view>\n",
+		},
+		{
+			name:   "space indent",
+			indent: 4,
+			nodes:  []*wxmlNode{elem("view", nil, elem("view", nil, elem("text", nil, text("a"))))},
+			want:   "<view>\n    <view>\n        <text>a</text>\n    </view>\n</view>\n",
+		},
+		{
+			name:   "wrap attributes",
+			indent: 2,
+			width:  30,
+			nodes: []*wxmlNode{elem("view", nil,
+				elem("view", []wxmlAttr{{Name: "class", Value: "container"}, {Name: "bindtap", Value: "onTap"}}, text("x")),
+				elem("image", []wxmlAttr{{Name: "src", Value: "a.png"}, {Name: "mode", Value: "aspectFit"}}),
+				elem("view", []wxmlAttr{{Name: "class", Value: "a-very-long-class-name"}}),
+			)},
+			want: "<view>\n" +
+				"  <view\n" +
+				"    class=\"container\"\n" +
+				"    bindtap=\"onTap\"\n" +
+				"  >x</view>\n" +
+				"  <image\n" +
+				"    src=\"a.png\"\n" +
+				"    mode=\"aspectFit\"\n" +
+				"  />\n" +
+				"  <view class=\"a-very-long-class-name\"></view>\n" +
+				"</view>\n",
+		},
+		{
+			name:   "wrap text",
+			indent: 2,
+			width:  20,
+			nodes:  []*wxmlNode{elem("text", nil, text("a long line of text"))},
+			want:   "<text>\n  a long line of text\n</text>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newWxmlWriter(tt.indent, tt.width).render(&wxmlNode{Children: tt.nodes})
+			if got != tt.want {
+				t.Errorf("render =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
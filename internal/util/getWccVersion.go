@@ -5,6 +5,9 @@ import (
 	"regexp"
 )
 
+// 定义正则表达式，用于匹配 __wcc_version__ 的值
+var wccVersionRegex = regexp.MustCompile(`__wcc_version__\s*=\s*['"]([^'"]+)['"]`)
+
 // GetWccVersion 从源代码字符串中提取 __wcc_version__ 的值
 func GetWccVersion(source string) string {
 	if source == "" {
@@ -14,11 +17,13 @@ func GetWccVersion(source string) string {
 	// 读取source文件内容
 	content, _ := os.ReadFile(source)
 
-	// 定义正则表达式，用于匹配 __wcc_version__ 的值
-	regex := regexp.MustCompile(`__wcc_version__\s*=\s*['"]([^'"]+)['"]`)
+	return GetWccVersionFromContent(string(content))
+}
 
+// GetWccVersionFromContent 从源代码内容中提取 __wcc_version__ 的值
+func GetWccVersionFromContent(content string) string {
 	// 查找匹配项
-	matches := regex.FindStringSubmatch(string(content))
+	matches := wccVersionRegex.FindStringSubmatch(content)
 
 	// 如果匹配成功并捕获到版本号，则返回版本号
 	if len(matches) > 1 {
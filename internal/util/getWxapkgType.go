@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -9,6 +10,22 @@ import (
 
 // GetWxapkgType 根据文件列表判断微信小程序包的类型
 func GetWxapkgType(fileList []string) WxapkgType {
+	wxapkgType, _ := GetWxapkgTypeEvidence(fileList)
+	return wxapkgType
+}
+
+// GetWxapkgTypeEvidence 根据文件列表判断微信小程序包的类型，并返回判断依据
+func GetWxapkgTypeEvidence(fileList []string) (WxapkgType, []string) {
+	var evidence []string
+	has := func(filename string) bool {
+		if containsFile(fileList, filename) {
+			evidence = append(evidence, fmt.Sprintf("包含 %s", filename))
+			return true
+		}
+		evidence = append(evidence, fmt.Sprintf("不包含 %s", filename))
+		return false
+	}
+
 	allFilesStartWithWA := true
 	for _, filename := range fileList {
 		if !strings.HasPrefix(filepath.Base(filename), "WA") {
@@ -18,47 +35,47 @@ func GetWxapkgType(fileList []string) WxapkgType {
 	}
 
 	if allFilesStartWithWA {
-		return FRAMEWORK
+		return FRAMEWORK, []string{"所有文件名均以 WA 开头"}
 	}
 
-	if containsFile(fileList, PageFrameHtml) {
-		if containsFile(fileList, CommonApp) {
-			return App_V4
+	if has(PageFrameHtml) {
+		if has(CommonApp) {
+			return App_V4, evidence
 		}
-		return App_V1
+		return App_V1, evidence
 	}
 
-	if containsFile(fileList, CommonApp) {
-		if containsFile(fileList, AppWxss) {
-			return App_V3
+	if has(CommonApp) {
+		if has(AppWxss) {
+			return App_V3, evidence
 		}
-		return APP_SUBPACKAGE_V2
+		return APP_SUBPACKAGE_V2, evidence
 	}
 
-	if containsFile(fileList, Page_Frame) {
-		if containsFile(fileList, AppWxss) {
-			return App_V2
+	if has(Page_Frame) {
+		if has(AppWxss) {
+			return App_V2, evidence
 		}
-		return APP_SUBPACKAGE_V1
+		return APP_SUBPACKAGE_V1, evidence
 	}
 
-	if containsFile(fileList, Game) {
-		if containsFile(fileList, App_Config) {
-			return GAME
+	if has(Game) {
+		if has(App_Config) {
+			return GAME, evidence
 		}
-		return GAME_SUBPACKAGE
+		return GAME_SUBPACKAGE, evidence
 	}
 
-	if containsFile(fileList, PluginJson) {
-		if containsFile(fileList, AppService) {
-			return APP_PLUGIN_V1
+	if has(PluginJson) {
+		if has(AppService) {
+			return APP_PLUGIN_V1, evidence
 		}
-		if containsFile(fileList, Plugin) {
-			return GAME_PLUGIN
+		if has(Plugin) {
+			return GAME_PLUGIN, evidence
 		}
 	}
 
-	return ""
+	return "", evidence
 }
 
 // containsFile 检查切片中是否包含特定文件名
@@ -0,0 +1,170 @@
+package verify
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Ackites/KillWxapkg/internal/unpack"
+	"github.com/Ackites/KillWxapkg/pkg/wxapkg"
+)
+
+// 问题类型
+const (
+	KindHeader      = "header"       // 文件头或索引无法读取
+	KindIndexLength = "index-length" // 索引段长度与实际不符
+	KindBodyLength  = "body-length"  // 数据段长度与文件或索引项不符
+	KindInfo1       = "info1"        // info1 不是 0
+	KindOutOfRange  = "out-of-range" // 文件超出数据段
+	KindOverlap     = "overlap"      // 文件之间重叠
+	KindGap         = "gap"          // 数据段中未被任何文件引用的字节
+	KindDuplicate   = "duplicate"    // 重复的文件名
+	KindUnsafeName  = "unsafe-name"  // 写入磁盘时需要重命名或会被拒绝的文件名
+)
+
+// Issue 包结构上的一个问题
+type Issue struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Name    string `json:"name,omitempty"`
+}
+
+// Verify 检查明文包的结构完整性，返回发现的所有问题，没有问题时返回空
+// size 为包的总长度，文件头或索引无法读取时只返回该问题
+func Verify(r io.ReaderAt, size int64) []Issue {
+	var issues []Issue
+	add := func(kind, name, format string, args ...interface{}) {
+		issues = append(issues, Issue{Kind: kind, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	header, files, problems, err := wxapkg.SalvageIndex(r, size)
+	if err != nil {
+		add(KindHeader, "", "%v", err)
+		return issues
+	}
+	// 索引项未能全部读取时无法继续检查，其余问题由下面的检查给出
+	if uint64(len(files)) < uint64(header.FileCount) {
+		for _, problem := range problems {
+			add(KindHeader, "", "%v", problem)
+		}
+		return issues
+	}
+
+	// 索引段实际结束位置，即数据段起始位置
+	indexEnd := int64(wxapkg.HeaderSize + 4)
+	for _, file := range files {
+		indexEnd += 12 + int64(file.NameLen)
+	}
+
+	// 索引段长度应包含文件数量
+	switch declared := wxapkg.HeaderSize + int64(header.IndexInfoLength); declared {
+	case indexEnd:
+	case indexEnd - 4:
+		add(KindIndexLength, "", "索引段长度 %d 未包含文件数量的 4 字节，应为 %d", header.IndexInfoLength, indexEnd-wxapkg.HeaderSize)
+	default:
+		add(KindIndexLength, "", "索引段长度 %d 与实际长度 %d 不符", header.IndexInfoLength, indexEnd-wxapkg.HeaderSize)
+	}
+
+	if header.Info1 != 0 {
+		add(KindInfo1, "", "info1 为 0x%08x，正常的包为 0", header.Info1)
+	}
+
+	// 数据段长度应与文件总长度一致，并覆盖所有文件
+	if body := size - indexEnd; int64(header.BodyInfoLength) != body {
+		add(KindBodyLength, "", "数据段长度 %d 与实际长度 %d 不符", header.BodyInfoLength, body)
+	}
+	var maxEnd int64
+	for _, file := range files {
+		maxEnd = max(maxEnd, int64(file.Offset)+int64(file.Size))
+	}
+	if maxEnd > indexEnd && maxEnd-indexEnd > int64(header.BodyInfoLength) {
+		add(KindBodyLength, "", "文件结束位置 %d 超出数据段长度 %d 声明的范围", maxEnd, header.BodyInfoLength)
+	}
+
+	issues = append(issues, checkRanges(files, indexEnd, size)...)
+	issues = append(issues, checkNames(files)...)
+	return issues
+}
+
+// checkRanges 检查文件是否超出数据段、互相重叠，以及数据段中未被引用的字节
+func checkRanges(files []wxapkg.WxapkgFile, bodyStart, size int64) []Issue {
+	var issues []Issue
+
+	sorted := append([]wxapkg.WxapkgFile(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	// covered 为已被文件覆盖的数据段结束位置，last 为覆盖到该位置的文件
+	covered := bodyStart
+	var last string
+	for _, file := range sorted {
+		start, end := int64(file.Offset), int64(file.Offset)+int64(file.Size)
+		if start < bodyStart || end > size {
+			issues = append(issues, Issue{Kind: KindOutOfRange, Name: file.Name,
+				Message: fmt.Sprintf("文件范围 [%d, %d) 超出数据段 [%d, %d)", start, end, bodyStart, size)})
+			continue
+		}
+		if file.Size == 0 {
+			continue
+		}
+		switch {
+		case start < covered:
+			issues = append(issues, Issue{Kind: KindOverlap, Name: file.Name,
+				Message: fmt.Sprintf("文件范围 [%d, %d) 与 %s 重叠 %d 字节", start, end, last, min(covered, end)-start)})
+		case start > covered:
+			issues = append(issues, gapIssue(covered, start))
+		}
+		if end > covered {
+			covered, last = end, file.Name
+		}
+	}
+	if covered < size {
+		issues = append(issues, gapIssue(covered, size))
+	}
+	return issues
+}
+
+// gapIssue 描述数据段中未被引用的区域
+func gapIssue(start, end int64) Issue {
+	return Issue{Kind: KindGap, Message: fmt.Sprintf("数据段中 [%d, %d) 共 %d 字节未被任何文件引用", start, end, end-start)}
+}
+
+// checkNames 检查重复的文件名及写入磁盘时不安全的文件名
+func checkNames(files []wxapkg.WxapkgFile) []Issue {
+	var issues []Issue
+	seen := make(map[string]int)
+	sanitizedBy := make(map[string]string)
+
+	for _, file := range files {
+		seen[file.Name]++
+		if seen[file.Name] == 2 {
+			issues = append(issues, Issue{Kind: KindDuplicate, Name: file.Name, Message: "文件名重复"})
+		}
+
+		if !strings.HasPrefix(file.Name, "/") {
+			issues = append(issues, Issue{Kind: KindUnsafeName, Name: file.Name, Message: "文件名不以 / 开头"})
+		}
+
+		sanitized, reasons, err := unpack.SanitizeName(file.Name)
+		if err != nil {
+			issues = append(issues, Issue{Kind: KindUnsafeName, Name: file.Name, Message: err.Error()})
+			continue
+		}
+		if len(reasons) > 0 {
+			issues = append(issues, Issue{Kind: KindUnsafeName, Name: file.Name,
+				Message: fmt.Sprintf("写入磁盘时将重命名为 %s: %s", sanitized, strings.Join(reasons, ", "))})
+		}
+
+		// 不同的文件名在不区分大小写的文件系统上或清理后指向同一路径
+		key := strings.ToLower(sanitized)
+		if other, ok := sanitizedBy[key]; ok && other != file.Name {
+			issues = append(issues, Issue{Kind: KindDuplicate, Name: file.Name,
+				Message: fmt.Sprintf("与 %s 写入磁盘时为同一路径", other)})
+		} else if !ok {
+			sanitizedBy[key] = file.Name
+		}
+	}
+	return issues
+}
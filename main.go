@@ -3,6 +3,8 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
+	"os"
 
 	"github.com/Ackites/KillWxapkg/internal/pack"
 
@@ -11,33 +13,69 @@ import (
 )
 
 var (
-	appID      string
-	input      string
-	outputDir  string
-	fileExt    string
-	restoreDir bool
-	pretty     bool
-	noClean    bool
-	hook       bool
-	save       bool
-	repack     string
-	watch      bool
-	sensitive  bool
+	appID       string
+	input       string
+	outputDir   string
+	fileExt     string
+	restoreDir  bool
+	pretty      bool
+	noClean     bool
+	hook        bool
+	save        bool
+	repack      string
+	watch       bool
+	sensitive   bool
+	private     bool
+	indent      int
+	width       int
+	info        bool
+	list        bool
+	cat         string
+	verifyMode  bool
+	jsonOutput  bool
+	include     string
+	exclude     string
+	salvage     bool
+	carve       bool
+	recoverMode bool
+	mobile      bool
+	discover    bool
+	all         bool
+	batch       string
+	conflict    string
 )
 
 func init() {
-	flag.StringVar(&appID, "id", "", "微信小程序的AppID")
-	flag.StringVar(&input, "in", "", "输入文件路径（多个文件用逗号分隔）或输入目录路径")
-	flag.StringVar(&outputDir, "out", "", "输出目录路径（如果未指定，则默认保存到输入目录下以AppID命名的文件夹）")
+	flag.StringVar(&appID, "id", "", "微信小程序的AppID（未指定时尝试从输入路径推断）")
+	flag.StringVar(&input, "in", "", "输入文件路径（多个文件用逗号分隔）、输入目录路径或 zip/tar/tar.gz 归档，- 表示从标准输入读取")
+	flag.StringVar(&outputDir, "out", "", "输出目录路径（如果未指定，则默认保存到输入目录下以AppID命名的文件夹），以 .zip/.tar.gz 结尾时输出为单个归档")
 	flag.StringVar(&fileExt, "ext", ".wxapkg", "处理的文件后缀")
 	flag.BoolVar(&restoreDir, "restore", false, "是否还原工程目录结构")
 	flag.BoolVar(&pretty, "pretty", false, "是否美化输出")
 	flag.BoolVar(&noClean, "noClean", false, "是否清理中间文件")
 	flag.BoolVar(&hook, "hook", false, "是否开启动态调试")
 	flag.BoolVar(&save, "save", false, "是否保存解密后的文件")
-	flag.StringVar(&repack, "repack", "", "重新打包wxapkg文件")
+	flag.StringVar(&repack, "repack", "", "重新打包wxapkg文件（同时指定-id时输出加密文件）")
 	flag.BoolVar(&watch, "watch", false, "是否监听将要打包的文件夹，并自动打包")
 	flag.BoolVar(&sensitive, "sensitive", false, "是否获取敏感数据")
+	flag.BoolVar(&private, "private", false, "还原工程目录结构时，额外生成关闭域名校验的project.private.config.json")
+	flag.IntVar(&indent, "indent", 0, "还原的WXML缩进的空格数，0表示使用制表符")
+	flag.IntVar(&width, "width", 100, "还原的WXML单行最大宽度，超出时属性分行，0表示不限制")
+	flag.BoolVar(&info, "info", false, "查看包的类型、索引及最大的文件，不解包")
+	flag.BoolVar(&list, "ls", false, "列出包内的文件索引，不解包")
+	flag.StringVar(&cat, "cat", "", "将包内指定文件的内容输出到标准输出")
+	flag.BoolVar(&verifyMode, "verify", false, "检查包的结构完整性（索引、数据段、文件名等），发现问题时以非零状态退出")
+	flag.BoolVar(&jsonOutput, "json", false, "以JSON格式输出-info、-ls、-verify、-batch的结果")
+	flag.StringVar(&include, "include", "", "仅解包匹配的文件（通配符，多个用逗号分隔）")
+	flag.StringVar(&exclude, "exclude", "", "不解包匹配的文件（通配符，多个用逗号分隔）")
+	flag.BoolVar(&carve, "carve", false, "从内存转储、备份、磁盘镜像等任意文件中查找并提取嵌入的wxapkg包")
+	flag.BoolVar(&recoverMode, "recover", false, "缺少AppID时，通过猜测异或密钥恢复加密包中的部分内容")
+	flag.BoolVar(&mobile, "mobile", false, "识别安卓/iOS缓存的包名（如_-1234567_56.wxapkg），按小程序和版本分组后解包还原")
+	flag.BoolVar(&discover, "discover", false, "遍历微信缓存目录（如Applet），查找所有wx…/<版本号>/目录并使用目录名中的AppID解包")
+	flag.BoolVar(&all, "all", false, "与-discover一起使用，处理每个小程序的所有版本（默认仅处理最新版本）")
+	flag.StringVar(&batch, "batch", "", "按YAML/JSON清单同时处理多个小程序，并输出汇总结果")
+	flag.StringVar(&conflict, "conflict", "overwrite", "多个包写入同一文件且内容不同时的处理方式：overwrite、keep-first、keep-both、fail")
+	flag.BoolVar(&salvage, "salvage", false, "宽松模式，尽可能解包损坏或被截断的包，并输出恢复报告")
 }
 
 func main() {
@@ -54,7 +92,10 @@ func main() {
                                                     
              Wxapkg Decompiler Tool v2.4.1
     `
-	fmt.Println(banner)
+	// 输出内容供脚本使用时不打印横幅
+	if cat == "" && !jsonOutput {
+		fmt.Println(banner)
+	}
 
 	// 动态调试
 	if hook {
@@ -64,17 +105,93 @@ func main() {
 
 	// 重新打包
 	if repack != "" {
-		pack.Repack(repack, watch, outputDir)
+		pack.Repack(repack, watch, outputDir, appID)
 		return
 	}
 
-	if appID == "" || input == "" {
-		fmt.Println("使用方法: program -id=<AppID> -in=<输入文件1,输入文件2> 或 -in=<输入目录> -out=<输出目录> [-ext=<文件后缀>] [-restore] [-pretty] [-noClean] [-hook] [-save] [-repack=<输入目录>] [-watch] [-sensitive]")
+	// 批量处理
+	if batch != "" {
+		if err := cmd.Batch(batch, options()); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if input == "" {
+		fmt.Println("使用方法: program [-id=<AppID>] -in=<输入文件1,输入文件2> 或 -in=<输入目录> -out=<输出目录> [-ext=<文件后缀>] [-restore] [-pretty] [-noClean] [-hook] [-save] [-repack=<输入目录>] [-watch] [-sensitive] [-private] [-indent=<空格数>] [-width=<宽度>] [-info] [-ls] [-cat=<包内文件>] [-verify] [-json] [-include=<通配符>] [-exclude=<通配符>] [-salvage] [-carve] [-recover] [-mobile] [-discover] [-all] [-batch=<清单文件>] [-conflict=<overwrite|keep-first|keep-both|fail>]")
 		flag.PrintDefaults()
 		fmt.Println()
 		return
 	}
 
+	// 提取嵌入的包
+	if carve {
+		if err := cmd.Carve(appID, input, outputDir, fileExt); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 无 AppID 恢复
+	if recoverMode {
+		if err := cmd.Recover(input, outputDir, fileExt); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 查看包信息
+	if info || list || cat != "" || verifyMode {
+		var err error
+		switch {
+		case cat != "":
+			err = cmd.Cat(appID, input, fileExt, cat)
+		case verifyMode:
+			err = cmd.Verify(appID, input, fileExt, jsonOutput)
+		case info:
+			err = cmd.Info(appID, input, fileExt, jsonOutput)
+		default:
+			err = cmd.List(appID, input, fileExt, jsonOutput)
+		}
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 执行命令
-	cmd.Execute(appID, input, outputDir, fileExt, restoreDir, pretty, noClean, save, sensitive)
+	if err := cmd.Execute(options()); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+// options 根据命令行参数生成解包选项
+func options() cmd.Options {
+	return cmd.Options{
+		AppID:         appID,
+		Input:         input,
+		OutputDir:     outputDir,
+		FileExt:       fileExt,
+		RestoreDir:    restoreDir,
+		Pretty:        pretty,
+		NoClean:       noClean,
+		Save:          save,
+		Sensitive:     sensitive,
+		PrivateConfig: private,
+		Indent:        indent,
+		Width:         width,
+		Include:       include,
+		Exclude:       exclude,
+		Salvage:       salvage,
+		Mobile:        mobile,
+		Discover:      discover,
+		All:           all,
+		Conflict:      conflict,
+		JSON:          jsonOutput,
+	}
 }
@@ -0,0 +1,328 @@
+// Package wxapkg 提供只读的 wxapkg 包访问接口
+//
+// Archive 在不写入磁盘的情况下解析索引并按需读取文件内容，同时实现了 fs.FS，
+// 可直接用于 fs.WalkDir、http.FS、template.ParseFS 等标准库接口。
+// 输入需为明文数据，加密包可先通过解密读取器转换。
+package wxapkg
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archive 只读的 wxapkg 包
+type Archive struct {
+	r      io.ReaderAt
+	size   int64
+	sized  bool // 长度是否已知，未知时按文件头推算，不可信
+	header Header
+	files  []WxapkgFile
+
+	entries map[string]*WxapkgFile // fs 路径 -> 索引项
+	dirs    map[string][]string    // 目录 fs 路径 -> 子项名称（已排序）
+}
+
+// 确保 Archive 实现了常用的 fs 接口
+var (
+	_ fs.FS         = (*Archive)(nil)
+	_ fs.ReadFileFS = (*Archive)(nil)
+	_ fs.ReadDirFS  = (*Archive)(nil)
+	_ fs.StatFS     = (*Archive)(nil)
+)
+
+// Open 解析 wxapkg 包
+// 如果 r 实现了 Size() int64（如 bytes.Reader、io.SectionReader）或 Stat()（如 os.File），使用其长度进行严格校验，否则根据文件头推算长度
+func Open(r io.ReaderAt) (*Archive, error) {
+	size := int64(-1)
+	switch s := r.(type) {
+	case interface{ Size() int64 }:
+		size = s.Size()
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := s.Stat(); err == nil && info.Mode().IsRegular() {
+			size = info.Size()
+		}
+	}
+	return NewArchive(r, size)
+}
+
+// NewArchive 使用指定长度解析 wxapkg 包，size 小于 0 表示长度未知
+func NewArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	header, files, err := ReadIndex(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	sized := size >= 0
+	if !sized {
+		for _, file := range files {
+			size = max(size, int64(file.Offset)+int64(file.Size))
+		}
+	}
+
+	a := &Archive{
+		r:       r,
+		size:    size,
+		sized:   sized,
+		header:  header,
+		files:   files,
+		entries: make(map[string]*WxapkgFile),
+		dirs:    map[string][]string{".": nil},
+	}
+	a.buildTree()
+	return a, nil
+}
+
+// buildTree 根据索引构建目录树，无法映射为合法 fs 路径的索引项仅保留在 Files 中
+func (a *Archive) buildTree() {
+	children := map[string]map[string]bool{".": {}}
+	for i := range a.files {
+		name := fsName(a.files[i].Name)
+		if name == "" || a.entries[name] != nil || children[name] != nil {
+			continue
+		}
+		// 与已存在的文件路径冲突的目录不再收录
+		conflict := false
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if a.entries[dir] != nil {
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			continue
+		}
+
+		a.entries[name] = &a.files[i]
+		for child := name; child != "."; child = path.Dir(child) {
+			parent := path.Dir(child)
+			if children[parent] == nil {
+				children[parent] = make(map[string]bool)
+			}
+			children[parent][path.Base(child)] = true
+		}
+	}
+
+	for dir, names := range children {
+		list := make([]string, 0, len(names))
+		for name := range names {
+			list = append(list, name)
+		}
+		sort.Strings(list)
+		a.dirs[dir] = list
+	}
+}
+
+// fsName 将索引中的文件名转换为 fs 路径（去掉开头的 /），不合法时返回空字符串
+func fsName(name string) string {
+	name = strings.TrimLeft(name, "/")
+	if name == "" || !fs.ValidPath(name) {
+		return ""
+	}
+	return name
+}
+
+// Header 返回文件头信息
+func (a *Archive) Header() Header {
+	return a.header
+}
+
+// Files 返回索引项列表（按包内顺序）
+func (a *Archive) Files() []WxapkgFile {
+	return a.files
+}
+
+// Names 返回包内所有文件名（按包内顺序）
+func (a *Archive) Names() []string {
+	names := make([]string, len(a.files))
+	for i, file := range a.files {
+		names[i] = file.Name
+	}
+	return names
+}
+
+// Size 返回包的总长度
+func (a *Archive) Size() int64 {
+	return a.size
+}
+
+// Entry 返回索引项对应的内容读取器，name 可带或不带开头的 /
+func (a *Archive) Entry(name string) (*io.SectionReader, error) {
+	file, ok := a.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return a.section(file), nil
+}
+
+// lookup 查找索引项
+func (a *Archive) lookup(name string) (*WxapkgFile, bool) {
+	if file, ok := a.entries[fsName(name)]; ok {
+		return file, true
+	}
+	for i := range a.files {
+		if a.files[i].Name == name {
+			return &a.files[i], true
+		}
+	}
+	return nil, false
+}
+
+func (a *Archive) section(file *WxapkgFile) *io.SectionReader {
+	return io.NewSectionReader(a.r, int64(file.Offset), int64(file.Size))
+}
+
+// Open 实现 fs.FS
+func (a *Archive) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if file, ok := a.entries[name]; ok {
+		return &entryFile{SectionReader: a.section(file), info: fileInfo{name: path.Base(name), size: int64(file.Size)}}, nil
+	}
+	if _, ok := a.dirs[name]; ok {
+		return &dirFile{archive: a, name: name, info: fileInfo{name: path.Base(name), dir: true}}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile 实现 fs.ReadFileFS
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	file, ok := a.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	// 长度已知时索引项的范围已经过校验，可以按索引中的大小分配；
+	// 长度未知时大小来自不可信的文件头，按实际读到的数据逐步分配
+	var data []byte
+	var err error
+	if a.sized {
+		data = make([]byte, file.Size)
+		_, err = io.ReadFull(a.section(file), data)
+	} else {
+		data, err = io.ReadAll(a.section(file))
+		if err == nil && uint64(len(data)) < uint64(file.Size) {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = ErrTruncated
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// ReadDir 实现 fs.ReadDirFS
+func (a *Archive) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if _, ok := a.dirs[name]; !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return a.dirEntries(name), nil
+}
+
+// Stat 实现 fs.StatFS
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	info, ok := a.stat(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return info, nil
+}
+
+func (a *Archive) stat(name string) (fileInfo, bool) {
+	if file, ok := a.entries[name]; ok {
+		return fileInfo{name: path.Base(name), size: int64(file.Size)}, true
+	}
+	if _, ok := a.dirs[name]; ok {
+		return fileInfo{name: path.Base(name), dir: true}, true
+	}
+	return fileInfo{}, false
+}
+
+func (a *Archive) dirEntries(dir string) []fs.DirEntry {
+	names := a.dirs[dir]
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, name := range names {
+		info, _ := a.stat(path.Join(dir, name))
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries
+}
+
+// fileInfo 实现 fs.FileInfo
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() any           { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// entryFile 包内文件，支持 Read、ReadAt、Seek
+type entryFile struct {
+	*io.SectionReader
+	info fileInfo
+}
+
+func (f *entryFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *entryFile) Close() error               { return nil }
+
+// dirFile 包内目录，实现 fs.ReadDirFile
+type dirFile struct {
+	archive *Archive
+	name    string
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = d.archive.dirEntries(d.name)
+	}
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
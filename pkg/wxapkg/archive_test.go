@@ -0,0 +1,120 @@
+package wxapkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// testFile 测试包中的文件
+type testFile struct {
+	name string
+	data string
+}
+
+// buildPackage 按 wxapkg 格式生成未加密的包
+func buildPackage(files []testFile) []byte {
+	var index, body bytes.Buffer
+	indexLength := 4 // FileCount
+	for _, file := range files {
+		indexLength += 4 + len(file.name) + 8
+	}
+	offset := HeaderSize + indexLength
+	for _, file := range files {
+		_ = binary.Write(&index, binary.BigEndian, uint32(len(file.name)))
+		index.WriteString(file.name)
+		_ = binary.Write(&index, binary.BigEndian, uint32(offset+body.Len()))
+		_ = binary.Write(&index, binary.BigEndian, uint32(len(file.data)))
+		body.WriteString(file.data)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(FirstMark)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(0))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(indexLength))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(body.Len()))
+	buf.WriteByte(LastMark)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(files)))
+	buf.Write(index.Bytes())
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+var testFiles = []testFile{
+	{"/app-config.json", `{"pages":["pages/index/index"]}`},
+	{"/app-service.js", "App({})"},
+	{"/pages/index/index.html", "<html></html>"},
+	{"/pages/index/empty.js", ""},
+	{"/static/img/logo.png", "\x89PNG"},
+}
+
+// readerAtOnly 只实现 io.ReaderAt，无法得知长度
+type readerAtOnly struct {
+	r io.ReaderAt
+}
+
+func (r readerAtOnly) ReadAt(p []byte, off int64) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+func TestArchiveFS(t *testing.T) {
+	archive, err := Open(bytes.NewReader(buildPackage(testFiles)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]string, len(testFiles))
+	for i, file := range testFiles {
+		expected[i] = file.name[1:]
+	}
+	if err := fstest.TestFS(archive, expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadFileTruncated(t *testing.T) {
+	data := buildPackage(testFiles)
+	truncated := data[:len(data)-2]
+
+	// 长度已知时在解析索引时发现截断
+	if _, err := Open(bytes.NewReader(truncated)); !errors.Is(err, ErrTruncated) && !errors.Is(err, ErrIndexOverflow) {
+		t.Fatalf("Open(truncated) = %v, want ErrTruncated or ErrIndexOverflow", err)
+	}
+
+	// 长度未知时读取被截断的文件返回 ErrTruncated
+	archive, err := Open(readerAtOnly{bytes.NewReader(truncated)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archive.ReadFile("static/img/logo.png"); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("ReadFile(truncated) = %v, want ErrTruncated", err)
+	}
+	content, err := archive.ReadFile("app-service.js")
+	if err != nil || string(content) != "App({})" {
+		t.Fatalf("ReadFile(app-service.js) = %q, %v", content, err)
+	}
+}
+
+func TestOpenFileUsesStat(t *testing.T) {
+	data := buildPackage(testFiles)
+	name := filepath.Join(t.TempDir(), "test.wxapkg")
+	if err := os.WriteFile(name, data[:len(data)-2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	// 通过 Stat 得知长度，按严格模式校验
+	if _, err := Open(f); err == nil {
+		t.Fatal("Open(truncated file) succeeded, want error")
+	}
+}
@@ -0,0 +1,44 @@
+package wxapkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrTruncated 文件长度不足，数据被截断
+	ErrTruncated = errors.New("文件长度不足，数据被截断")
+	// ErrBadMagic 文件头标记不正确
+	ErrBadMagic = errors.New("文件头标记不正确")
+	// ErrIndexOverflow 索引中的偏移量或长度超出有效范围
+	ErrIndexOverflow = errors.New("索引越界")
+)
+
+// IndexOverflowError 描述越界的索引项及其偏移量
+type IndexOverflowError struct {
+	Name   string // 文件名，索引段本身越界时为空
+	Offset uint64 // 起始偏移量
+	End    uint64 // 结束偏移量
+	Limit  uint64 // 允许的最大偏移量
+}
+
+func (e *IndexOverflowError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%v: 索引段结束位置 %d 与预期位置 %d 不符", ErrIndexOverflow, e.End, e.Limit)
+	}
+	return fmt.Sprintf("%v: 文件 %s 的范围 [%d, %d) 超出了上限 %d", ErrIndexOverflow, e.Name, e.Offset, e.End, e.Limit)
+}
+
+// Is 使 errors.Is(err, ErrIndexOverflow) 成立
+func (e *IndexOverflowError) Is(target error) bool {
+	return target == ErrIndexOverflow
+}
+
+// readError 包装读取错误，数据不足时归为 ErrTruncated
+func readError(msg string, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%s: %w", msg, ErrTruncated)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,244 @@
+package wxapkg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// FirstMark 文件头首标记
+	FirstMark = 0xBE
+	// LastMark 文件头尾标记
+	LastMark = 0xED
+	// HeaderSize 文件头长度（首标记、info1、索引段长度、数据段长度、尾标记）
+	HeaderSize = 14
+	// MaxNameLen 文件名的最大长度
+	MaxNameLen = 1024
+)
+
+// Header wxapkg 文件头
+type Header struct {
+	Info1           uint32
+	IndexInfoLength uint32
+	BodyInfoLength  uint32
+	FileCount       uint32
+}
+
+// WxapkgFile wxapkg 索引项
+type WxapkgFile struct {
+	NameLen uint32
+	Name    string
+	Offset  uint32
+	Size    uint32
+}
+
+// indexReader 带位置计数的缓冲读取器，用于顺序读取文件头和索引段
+type indexReader struct {
+	r   *bufio.Reader
+	pos uint64
+}
+
+func (ir *indexReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	ir.pos += uint64(n)
+	return n, err
+}
+
+// newIndexReader 创建从头开始读取的索引读取器，size 小于 0 时不限制长度
+func newIndexReader(r io.ReaderAt, size int64) *indexReader {
+	if size < 0 {
+		size = 1<<63 - 1
+	}
+	return &indexReader{r: bufio.NewReader(io.NewSectionReader(r, 0, size))}
+}
+
+// readHeader 读取并校验文件头及文件数量
+func readHeader(reader *indexReader) (Header, error) {
+	var header Header
+
+	var firstMark byte
+	if err := binary.Read(reader, binary.BigEndian, &firstMark); err != nil {
+		return header, readError("读取首标记失败", err)
+	}
+	if firstMark != FirstMark {
+		return header, fmt.Errorf("无效的wxapkg文件: 首标记不正确: %w", ErrBadMagic)
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &header.Info1); err != nil {
+		return header, readError("读取info1失败", err)
+	}
+	if err := binary.Read(reader, binary.BigEndian, &header.IndexInfoLength); err != nil {
+		return header, readError("读取索引段长度失败", err)
+	}
+	if err := binary.Read(reader, binary.BigEndian, &header.BodyInfoLength); err != nil {
+		return header, readError("读取数据段长度失败", err)
+	}
+
+	var lastMark byte
+	if err := binary.Read(reader, binary.BigEndian, &lastMark); err != nil {
+		return header, readError("读取尾标记失败", err)
+	}
+	if lastMark != LastMark {
+		return header, fmt.Errorf("无效的wxapkg文件: 尾标记不正确: %w", ErrBadMagic)
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &header.FileCount); err != nil {
+		return header, readError("读取文件数量失败", err)
+	}
+
+	return header, nil
+}
+
+// readEntry 读取单个索引项
+func readEntry(reader *indexReader) (WxapkgFile, error) {
+	var file WxapkgFile
+
+	if err := binary.Read(reader, binary.BigEndian, &file.NameLen); err != nil {
+		return file, readError("读取文件名长度失败", err)
+	}
+
+	if file.NameLen == 0 || file.NameLen > MaxNameLen {
+		return file, fmt.Errorf("文件名长度 %d 不合理", file.NameLen)
+	}
+
+	nameBytes := make([]byte, file.NameLen)
+	if _, err := io.ReadFull(reader, nameBytes); err != nil {
+		return file, readError("读取文件名失败", err)
+	}
+	file.Name = string(nameBytes)
+
+	if err := binary.Read(reader, binary.BigEndian, &file.Offset); err != nil {
+		return file, readError("读取文件偏移量失败", err)
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &file.Size); err != nil {
+		return file, readError("读取文件大小失败", err)
+	}
+
+	return file, nil
+}
+
+// 长度未知时索引项列表预分配的最大容量
+const maxEntryCapacity = 4096
+
+// entryCapacity 返回索引项列表的初始容量
+// 长度未知时文件数量无法与数据长度比对，只预分配有限的容量，其余随读取增长
+func entryCapacity(count uint64, size int64) uint64 {
+	if size < 0 {
+		return min(count, maxEntryCapacity)
+	}
+	return count
+}
+
+// ReadIndex 读取并校验文件头和索引段
+// size 为数据总长度，小于 0 时表示长度未知，此时以索引段实际结束位置加数据段长度作为总长度
+func ReadIndex(r io.ReaderAt, size int64) (Header, []WxapkgFile, error) {
+	reader := newIndexReader(r, size)
+
+	// 读取文件头
+	header, err := readHeader(reader)
+	if err != nil {
+		return header, nil, err
+	}
+
+	// 验证长度的合理性
+	if size >= 0 {
+		totalLength := uint64(header.IndexInfoLength) + uint64(header.BodyInfoLength)
+		if totalLength > uint64(size) {
+			return header, nil, fmt.Errorf("%w: 索引段(%d) + 数据段(%d) > 文件总长度(%d)", ErrTruncated, header.IndexInfoLength, header.BodyInfoLength, size)
+		}
+	}
+
+	// 计算索引段的预期结束位置，长度未知时以文件头声明的索引段长度为上限，索引段长度包含文件数量
+	indexStart := reader.pos
+	var expectedIndexEnd uint64
+	if size >= 0 {
+		expectedIndexEnd = uint64(size) - uint64(header.BodyInfoLength)
+	} else {
+		expectedIndexEnd = HeaderSize + uint64(header.IndexInfoLength)
+	}
+
+	// 每个索引项至少 13 字节（文件名长度、至少 1 字节文件名、偏移量、大小），据此限制文件数量
+	if expectedIndexEnd < indexStart || uint64(header.FileCount)*13 > expectedIndexEnd-indexStart {
+		return header, nil, &IndexOverflowError{Offset: indexStart, End: indexStart + uint64(header.FileCount)*13, Limit: expectedIndexEnd}
+	}
+
+	// 读取索引
+	fileList := make([]WxapkgFile, 0, entryCapacity(uint64(header.FileCount), size))
+	for i := uint32(0); i < header.FileCount; i++ {
+		file, err := readEntry(reader)
+		if err != nil {
+			return header, nil, err
+		}
+		fileList = append(fileList, file)
+
+		// 验证我们是否仍在索引段内
+		if reader.pos > expectedIndexEnd {
+			return header, nil, &IndexOverflowError{Offset: indexStart, End: reader.pos, Limit: expectedIndexEnd}
+		}
+	}
+
+	// 验证是否正确读完了整个索引段
+	indexEnd := reader.pos
+	if size >= 0 && indexEnd != expectedIndexEnd {
+		return header, nil, &IndexOverflowError{Offset: indexStart, End: indexEnd, Limit: expectedIndexEnd}
+	}
+
+	// 验证文件偏移量和大小
+	totalLength := indexEnd + uint64(header.BodyInfoLength)
+	if size >= 0 {
+		totalLength = uint64(size)
+	}
+	for _, file := range fileList {
+		fileEnd := uint64(file.Offset) + uint64(file.Size)
+		if fileEnd > totalLength {
+			return header, nil, &IndexOverflowError{Name: file.Name, Offset: uint64(file.Offset), End: fileEnd, Limit: totalLength}
+		}
+	}
+
+	return header, fileList, nil
+}
+
+// SalvageIndex 宽松地读取索引段，用于损坏或被截断的包
+// 只要文件头有效就尽可能多地读取索引项，遇到无法读取的索引项时停止；
+// 不校验索引段和数据段长度，也不校验文件范围，发现的问题通过 problems 返回
+func SalvageIndex(r io.ReaderAt, size int64) (header Header, fileList []WxapkgFile, problems []error, err error) {
+	reader := newIndexReader(r, size)
+
+	header, err = readHeader(reader)
+	if err != nil {
+		return header, nil, nil, err
+	}
+
+	truncated := size >= 0 && uint64(header.IndexInfoLength)+uint64(header.BodyInfoLength) > uint64(size)
+	if truncated {
+		problems = append(problems, fmt.Errorf("%w: 索引段(%d) + 数据段(%d) > 文件总长度(%d)", ErrTruncated, header.IndexInfoLength, header.BodyInfoLength, size))
+	}
+
+	// 按剩余长度限制文件数量，避免分配过大的列表
+	count := uint64(header.FileCount)
+	if size >= 0 {
+		count = min(count, (uint64(max(size, int64(reader.pos)))-reader.pos)/13)
+	}
+	if count < uint64(header.FileCount) {
+		problems = append(problems, fmt.Errorf("%w: 文件数量 %d 超出剩余数据可容纳的数量 %d", ErrIndexOverflow, header.FileCount, count))
+	}
+
+	fileList = make([]WxapkgFile, 0, entryCapacity(count, size))
+	for i := uint64(0); i < count; i++ {
+		file, err := readEntry(reader)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("第 %d 个索引项: %w", i+1, err))
+			break
+		}
+		fileList = append(fileList, file)
+	}
+
+	if size >= 0 && !truncated && reader.pos+uint64(header.BodyInfoLength) != uint64(size) {
+		problems = append(problems, fmt.Errorf("索引段结束位置 %d 加数据段长度 %d 与文件总长度 %d 不符", reader.pos, header.BodyInfoLength, size))
+	}
+
+	return header, fileList, problems, nil
+}
@@ -0,0 +1,63 @@
+package wxapkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// FuzzReadIndex 任意输入都不应导致 panic
+func FuzzReadIndex(f *testing.F) {
+	data := buildPackage(testFiles)
+	f.Add(data)
+	f.Add(data[:HeaderSize])
+	f.Add(data[:len(data)-3])
+	f.Add([]byte{FirstMark})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := bytes.NewReader(data)
+		_, _, _ = ReadIndex(r, int64(len(data)))
+		_, _, _ = ReadIndex(r, -1)
+		_, _, _, _ = SalvageIndex(r, int64(len(data)))
+
+		archive, err := Open(r)
+		if err != nil {
+			return
+		}
+		_ = fs.WalkDir(archive, ".", func(name string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				_, _ = archive.ReadFile(name)
+			}
+			return nil
+		})
+	})
+}
+
+// TestReadIndexUnknownSizeOverrun 长度未知时索引项超出文件头声明的索引段长度应报错
+func TestReadIndexUnknownSizeOverrun(t *testing.T) {
+	data := buildPackage(testFiles)
+	indexLength := binary.BigEndian.Uint32(data[5:9])
+
+	for overrun := uint32(0); overrun <= 4; overrun++ {
+		damaged := bytes.Clone(data)
+		binary.BigEndian.PutUint32(damaged[5:9], indexLength-overrun)
+
+		_, files, err := ReadIndex(bytes.NewReader(damaged), -1)
+		if overrun == 0 {
+			if err != nil || len(files) != len(testFiles) {
+				t.Fatalf("ReadIndex() = %d files, %v", len(files), err)
+			}
+			continue
+		}
+		var overflow *IndexOverflowError
+		if !errors.As(err, &overflow) {
+			t.Fatalf("overrun %d: ReadIndex() error = %v, want IndexOverflowError", overrun, err)
+		}
+		if want := uint64(HeaderSize + indexLength - overrun); overflow.Limit != want {
+			t.Errorf("overrun %d: Limit = %d, want %d", overrun, overflow.Limit, want)
+		}
+	}
+}